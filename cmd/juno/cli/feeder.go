@@ -0,0 +1,104 @@
+package cli
+
+// notest
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/NethermindEth/juno/internal/config"
+	"github.com/NethermindEth/juno/internal/log"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feedertest"
+	"github.com/spf13/cobra"
+)
+
+// Cobra configuration of the feeder commands.
+var (
+	// recordOut is the directory where the recorded responses are written.
+	recordOut string
+	// recordFrom and recordTo are the first and last blocks to record.
+	recordFrom, recordTo uint64
+	// recordGateway is the URL of the feeder gateway to record. If empty,
+	// the network of the configuration is used.
+	recordGateway string
+
+	// feederCmd groups the commands to work with the feeder gateway.
+	feederCmd = &cobra.Command{
+		Use:   "feeder",
+		Short: "Work with the StarkNet feeder gateway.",
+	}
+
+	// recordCmd records the responses of the feeder gateway to a fixture
+	// archive.
+	recordCmd = &cobra.Command{
+		Use:   "record",
+		Short: "Record the feeder gateway responses for a range of blocks.",
+		Long: `Record the responses of the feeder gateway for a range of blocks to a fixture archive.
+
+For each block, the block itself, its state update and the full definition of
+the contracts deployed in it are requested, as the synchronization does, as
+well as the code of those contracts. Every response is
+written to the output directory, which can be served again without network
+access with the feedertest package (see feedertest.Dir and
+feedertest.NewReplayClient).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if recordTo < recordFrom {
+				return fmt.Errorf("the last block %d is before the first block %d", recordTo, recordFrom)
+			}
+			gateway := recordGateway
+			if gateway == "" {
+				gateway = config.Runtime.Network
+			}
+			if err := os.MkdirAll(recordOut, 0o755); err != nil {
+				return err
+			}
+
+			var recorder feeder.HttpClient = feedertest.NewRecorder(&http.Client{Timeout: time.Minute}, recordOut)
+			client := feeder.NewClient(gateway, "/feeder_gateway", &recorder)
+			return recordBlocks(cmd.Context(), client, recordFrom, recordTo)
+		},
+	}
+)
+
+func init() {
+	recordCmd.Flags().StringVar(&recordOut, "out", "", "directory where the responses are recorded")
+	recordCmd.Flags().Uint64Var(&recordFrom, "from", 0, "first block to record")
+	recordCmd.Flags().Uint64Var(&recordTo, "to", 0, "last block to record")
+	recordCmd.Flags().StringVar(&recordGateway, "gateway", "", "URL of the feeder gateway (default is the configured network)")
+	if err := recordCmd.MarkFlagRequired("out"); err != nil {
+		panic(err)
+	}
+	feederCmd.AddCommand(recordCmd)
+	rootCmd.AddCommand(feederCmd)
+}
+
+// recordBlocks requests every block in the given range, its state update and
+// the full definition and the code of the contracts deployed in it with the
+// given client.
+func recordBlocks(ctx context.Context, client *feeder.Client, from, to uint64) error {
+	for blockNumber := from; blockNumber <= to; blockNumber++ {
+		id := feeder.BlockNumber(blockNumber)
+		if _, err := client.GetBlock(ctx, id); err != nil {
+			return fmt.Errorf("block %d: %w", blockNumber, err)
+		}
+		update, err := client.GetStateUpdate(ctx, id)
+		if err != nil {
+			return fmt.Errorf("state update of block %d: %w", blockNumber, err)
+		}
+		for _, contract := range update.StateDiff.DeployedContracts {
+			if _, err := client.GetFullContract(ctx, contract.Address, id); err != nil {
+				return fmt.Errorf("full contract %s: %w", contract.Address, err)
+			}
+			if _, err := client.GetCode(ctx, contract.Address, id); err != nil {
+				return fmt.Errorf("code of contract %s: %w", contract.Address, err)
+			}
+		}
+		log.Default.With("Block Number", blockNumber, "Deployed Contracts", len(update.StateDiff.DeployedContracts)).
+			Info("Block recorded.")
+	}
+	return nil
+}
@@ -2,18 +2,23 @@ package cli
 
 // notest
 import (
+	"context"
 	_ "embed"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"syscall"
+	"time"
 
 	"github.com/NethermindEth/juno/internal/config"
 	"github.com/NethermindEth/juno/internal/errpkg"
 	"github.com/NethermindEth/juno/internal/log"
 	"github.com/NethermindEth/juno/internal/process"
+	"github.com/NethermindEth/juno/internal/services"
+	"github.com/NethermindEth/juno/pkg/feeder"
 	"github.com/NethermindEth/juno/pkg/rpc"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -45,21 +50,32 @@ var (
 				<-sig
 				log.Default.Info("Trying to close...")
 				handler.Close()
+				closeStorageServices()
 				log.Default.Info("App closing...Bye!!!")
 				os.Exit(0)
 			}()
 
+			// Start the storage services before any process that depends
+			// on them.
+			runStorageServices()
+
 			// Subscribe the RPC client to the main loop if it is enabled in
 			// the config.
 			if config.Runtime.RPC.Enabled {
-				s := rpc.NewServer(":" + strconv.Itoa(config.Runtime.RPC.Port))
+				s := rpc.NewServer(":"+strconv.Itoa(config.Runtime.RPC.Port), &services.SyncService, &services.SyncService)
 				handler.Add("RPC", s.ListenAndServe, s.Close)
 			}
 
+			// Subscribe the synchronization with the feeder gateway to the
+			// main loop.
+			setupSyncService()
+			handler.Add("Sync Service", services.SyncService.Run, services.SyncService.Close)
+
 			// endless running process
 			log.Default.Info("Starting all processes...")
 			handler.Run()
 			handler.Close()
+			closeStorageServices()
 			log.Default.Info("App closing...Bye!!!")
 		},
 	}
@@ -120,9 +136,59 @@ func initConfig() {
 		"Database Path", config.Runtime.DbPath,
 		"Rpc Port", config.Runtime.RPC.Port,
 		"Rpc Enabled", config.Runtime.RPC.Enabled,
+		"Sync Concurrency", config.Runtime.Sync.Concurrency,
+		"Sync Trie History", config.Runtime.Sync.TrieHistory,
 	).Info("Config values.")
 }
 
+// setupSyncService configures the SyncService with the runtime config. The
+// chain is derived from the feeder gateway of the network, and left to the
+// default of the service if the gateway is not known.
+func setupSyncService() {
+	services.SyncService.SetConcurrency(config.Runtime.Sync.Concurrency)
+	services.SyncService.SetTrieHistory(config.Runtime.Sync.TrieHistory)
+	if chainID, ok := feeder.NetworkChainID(config.Runtime.Network); ok {
+		services.SyncService.SetChainID(chainID)
+	} else {
+		log.Default.With("Network", config.Runtime.Network).Warn("Unknown network, using the default chain.")
+	}
+}
+
+// storageServices are the services that give access to the database and
+// must be running while any other process is running.
+var storageServices = []services.Service{
+	&services.BlockService,
+	&services.TransactionService,
+	&services.StateService,
+	&services.AbiService,
+}
+
+// runStorageServices starts all the storage services.
+func runStorageServices() {
+	for _, s := range storageServices {
+		err := s.Run()
+		errpkg.CheckFatal(err, "Failed to start a storage service.")
+	}
+}
+
+// closeStorageOnce makes closeStorageServices stop the storage services
+// only once.
+var closeStorageOnce sync.Once
+
+// closeStorageServices stops all the storage services. It may be called
+// from both the signal handler and the normal exit path; only the first
+// call stops them, and the others wait for it to end.
+func closeStorageServices() {
+	closeStorageOnce.Do(func() {
+		for _, s := range storageServices {
+			ctx, cancel := context.WithDeadline(
+				context.Background(), time.Now().Add(5*time.Second))
+			s.Close(ctx)
+			cancel()
+		}
+	})
+}
+
 // Execute handle flags for Cobra execution.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/juno/internal/config"
+	"github.com/NethermindEth/juno/internal/services"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+func TestSetupSyncService(t *testing.T) {
+	runtime := config.Runtime
+	t.Cleanup(func() {
+		config.Runtime = runtime
+		services.SyncService.SetChainID("")
+	})
+
+	tests := [...]struct {
+		network string
+		want    feeder.ChainID
+	}{
+		{"https://alpha-mainnet.starknet.io", feeder.Mainnet},
+		{"http://alpha4.starknet.io", feeder.Testnet},
+	}
+	for _, test := range tests {
+		config.Runtime = &config.Config{Network: test.network}
+		setupSyncService()
+		if got := services.SyncService.ChainID(); got != test.want {
+			t.Errorf("unexpected chain ID %q for network %s, want %q", got, test.network, test.want)
+		}
+	}
+}
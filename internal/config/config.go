@@ -19,11 +19,22 @@ type rpcConfig struct {
 	Port    int  `yaml:"port" mapstructure:"port"`
 }
 
+// syncConfig represents the juno synchronization configuration.
+type syncConfig struct {
+	// Concurrency is the number of blocks downloaded in parallel from the
+	// feeder gateway.
+	Concurrency int `yaml:"concurrency" mapstructure:"concurrency"`
+	// TrieHistory is the number of past roots kept by each state trie, or
+	// zero to keep them all.
+	TrieHistory int `yaml:"trie_history" mapstructure:"trie_history"`
+}
+
 // Config represents the juno configuration.
 type Config struct {
-	RPC     rpcConfig `yaml:"rpc" mapstructure:"rpc"`
-	DbPath  string    `yaml:"db_path" mapstructure:"db_path"`
-	Network string    `yaml:"starknet_network" mapstructure:"starknet_network"`
+	RPC     rpcConfig  `yaml:"rpc" mapstructure:"rpc"`
+	Sync    syncConfig `yaml:"sync" mapstructure:"sync"`
+	DbPath  string     `yaml:"db_path" mapstructure:"db_path"`
+	Network string     `yaml:"starknet_network" mapstructure:"starknet_network"`
 }
 
 var (
@@ -83,6 +94,7 @@ func New() {
 	}
 	data, err := yaml.Marshal(&Config{
 		RPC:     rpcConfig{Enabled: false, Port: 8080},
+		Sync:    syncConfig{Concurrency: 8},
 		DbPath:  Dir,
 		Network: goerli,
 	})
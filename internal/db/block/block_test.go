@@ -57,6 +57,36 @@ func TestManager_PutBlock(t *testing.T) {
 	manager.Close()
 }
 
+func TestManager_DeleteBlock(t *testing.T) {
+	orphan := &Block{
+		Hash:            fromHexString("43950c9e3565cba1f2627b219d4863380f93a8548818ce26019d1bd5eebb0fb"),
+		BlockNumber:     2175,
+		ParentBlockHash: fromHexString("f8fe26de3ce9ee4d543b1152deb2ce549e589524d79598227761d6006b74a9"),
+	}
+	canonical := &Block{
+		Hash:            fromHexString("5ce76214481ebb29f912cb5d31abdff34fd42217f5ece9dda76d9fcfd62dc73"),
+		BlockNumber:     2175,
+		ParentBlockHash: fromHexString("f8fe26de3ce9ee4d543b1152deb2ce549e589524d79598227761d6006b74a9"),
+	}
+	manager := NewManager(db.NewKeyValueDb(t.TempDir(), 0))
+	manager.PutBlock(orphan.Hash, orphan)
+	manager.PutBlock(canonical.Hash, canonical)
+	// The number index points to the canonical block, so deleting the orphan
+	// block must keep it
+	manager.DeleteBlock(orphan.Hash)
+	if manager.GetBlockByHash(orphan.Hash) != nil {
+		t.Errorf("block %s found after delete", hex.EncodeToString(orphan.Hash))
+	}
+	if !equalData(t, canonical, manager.GetBlockByNumber(canonical.BlockNumber)) {
+		t.Errorf("block number index changed after deleting another block")
+	}
+	manager.DeleteBlock(canonical.Hash)
+	if manager.GetBlockByNumber(canonical.BlockNumber) != nil {
+		t.Errorf("block %d found after delete", canonical.BlockNumber)
+	}
+	manager.Close()
+}
+
 func equalData(t *testing.T, a, b *Block) bool {
 	aData, err := proto.Marshal(a)
 	if err != nil {
@@ -0,0 +1,57 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// ErrInvalidHash is matched by the errors returned when the hash of a block
+// doesn't commit to its header. Use errors.Is to check for it.
+var ErrInvalidHash = errors.New("invalid block hash")
+
+// CalculateHash returns the hash of the block, computed from its header as
+//
+//	h(block_number, global_state_root, sequencer_address, timestamp,
+//	  tx_count, tx_commitment, event_count, event_commitment, 0, 0,
+//	  parent_block_hash)
+//
+// where h is the Pedersen hash of an array. The zeros are reserved for the
+// protocol version and extra data.
+//
+// This is the hash of the blocks produced since StarkNet 0.7.0. The earlier
+// blocks were hashed with a legacy formula, which commits to the chain ID
+// but not to the sequencer address, the timestamp or the events. It is not
+// implemented, so the hash of those blocks can't be verified.
+func (x *Block) CalculateHash() []byte {
+	zero := new(big.Int)
+	hash := pedersen.ArrayDigest(
+		new(big.Int).SetUint64(x.BlockNumber),
+		new(big.Int).SetBytes(x.GlobalStateRoot),
+		new(big.Int).SetBytes(x.SequencerAddress),
+		big.NewInt(x.TimeStamp),
+		new(big.Int).SetUint64(x.TxCount),
+		new(big.Int).SetBytes(x.TxCommitment),
+		new(big.Int).SetUint64(x.EventCount),
+		new(big.Int).SetBytes(x.EventCommitment),
+		zero,
+		zero,
+		new(big.Int).SetBytes(x.ParentBlockHash),
+	)
+	return common.BigToFelt(hash).Bytes()
+}
+
+// VerifyHash checks that the Hash of the block is the hash of its header, as
+// returned by CalculateHash. It fails for the blocks produced before
+// StarkNet 0.7.0, which must not be verified.
+func (x *Block) VerifyHash() error {
+	hash := x.CalculateHash()
+	if !bytes.Equal(common.BytesToFelt(x.Hash).Bytes(), hash) {
+		return fmt.Errorf("%w: block %d has hash %x, computed %x", ErrInvalidHash, x.BlockNumber, x.Hash, hash)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package block
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// chainHash returns the hash of an array as the StarkNet specification defines
+// it, h(h(h(h(0, data[0]), data[1]), ...), len(data)) with h the Pedersen hash
+// of two elements.
+func chainHash(data ...*big.Int) *big.Int {
+	hash := new(big.Int)
+	for _, x := range data {
+		hash = pedersen.Digest(hash, x)
+	}
+	return pedersen.Digest(hash, big.NewInt(int64(len(data))))
+}
+
+func TestBlock_CalculateHash(t *testing.T) {
+	b := &Block{
+		BlockNumber:      2175,
+		ParentBlockHash:  fromHexString("f8fe26de3ce9ee4d543b1152deb2ce549e589524d79598227761d6006b74a9"),
+		SequencerAddress: fromHexString("1"),
+		GlobalStateRoot:  fromHexString("6a42d697b5b735eef03bb71841ed5099d57088f7b5eec8e356fe2601d5ba08f"),
+		TimeStamp:        1652488132,
+		TxCount:          2,
+		TxCommitment:     fromHexString("2"),
+		EventCount:       19,
+		EventCommitment:  fromHexString("3"),
+	}
+	want := chainHash(
+		big.NewInt(2175),
+		new(big.Int).SetBytes(b.GlobalStateRoot),
+		big.NewInt(1),
+		big.NewInt(1652488132),
+		big.NewInt(2),
+		big.NewInt(2),
+		big.NewInt(19),
+		big.NewInt(3),
+		big.NewInt(0),
+		big.NewInt(0),
+		new(big.Int).SetBytes(b.ParentBlockHash),
+	)
+	hash := b.CalculateHash()
+	if new(big.Int).SetBytes(hash).Cmp(want) != 0 {
+		t.Fatalf("unexpected hash %x, want %x", hash, want)
+	}
+
+	b.Hash = want.Bytes()
+	if err := b.VerifyHash(); err != nil {
+		t.Errorf("unexpected error verifying the hash: %s", err)
+	}
+	if !bytes.Equal(b.CalculateHash(), hash) {
+		t.Errorf("the hash depends on the hash field")
+	}
+
+	// The hash must commit to every field of the header.
+	for name, tamper := range map[string]func(b *Block){
+		"block number":      func(b *Block) { b.BlockNumber++ },
+		"parent block hash": func(b *Block) { b.ParentBlockHash = fromHexString("1") },
+		"sequencer address": func(b *Block) { b.SequencerAddress = fromHexString("2") },
+		"state root":        func(b *Block) { b.GlobalStateRoot = fromHexString("1") },
+		"timestamp":         func(b *Block) { b.TimeStamp++ },
+		"tx count":          func(b *Block) { b.TxCount++ },
+		"tx commitment":     func(b *Block) { b.TxCommitment = fromHexString("1") },
+		"event count":       func(b *Block) { b.EventCount++ },
+		"event commitment":  func(b *Block) { b.EventCommitment = fromHexString("1") },
+	} {
+		tampered := &Block{
+			Hash:             b.Hash,
+			BlockNumber:      b.BlockNumber,
+			ParentBlockHash:  b.ParentBlockHash,
+			SequencerAddress: b.SequencerAddress,
+			GlobalStateRoot:  b.GlobalStateRoot,
+			TimeStamp:        b.TimeStamp,
+			TxCount:          b.TxCount,
+			TxCommitment:     b.TxCommitment,
+			EventCount:       b.EventCount,
+			EventCommitment:  b.EventCommitment,
+		}
+		tamper(tampered)
+		if err := tampered.VerifyHash(); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("unexpected error %v verifying a block with a different %s", err, name)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package block
 
 import (
+	"bytes"
 	"encoding/binary"
 
 	"github.com/NethermindEth/juno/internal/db"
@@ -99,6 +100,33 @@ func (manager *Manager) PutBlock(blockHash []byte, block *Block) {
 	}
 }
 
+// DeleteBlock removes the block with the given hash. If the block number index
+// points to the deleted block, the index entry is also removed. If the block
+// does not exist then nothing is done. If any error happens, then panic.
+func (manager *Manager) DeleteBlock(blockHash []byte) {
+	block := manager.GetBlockByHash(blockHash)
+	if block == nil {
+		return
+	}
+	hashKey := buildHashKey(blockHash)
+	numberKey := buildNumberKey(block.BlockNumber)
+	// Remove the number index only if it belongs to this block
+	indexedHashKey, err := manager.database.Get(numberKey)
+	if err != nil {
+		panic(any(err))
+	}
+	if bytes.Equal(indexedHashKey, hashKey) {
+		err = manager.database.Delete(numberKey)
+		if err != nil {
+			panic(any(err))
+		}
+	}
+	err = manager.database.Delete(hashKey)
+	if err != nil {
+		panic(any(err))
+	}
+}
+
 func (manager *Manager) Close() {
 	manager.database.Close()
 }
@@ -1,49 +1,144 @@
 package db
 
-// KeyValueStore implement the Storer interface that use a Databaser
+import (
+	"errors"
+	"runtime"
+)
+
+// ErrTxnStarted is returned by KeyValueStore.Begin when a transaction is
+// already in progress.
+var ErrTxnStarted = errors.New("db: transaction already started")
+
+// KeyValueStore implement the Storer interface that use a Databaser.
+//
+// Between Begin and Commit, every read and write goes through a single
+// mdbx write transaction, so the writes are applied all at once on
+// Commit, or not at all on Rollback or if the process stops before.
+// Outside a transaction each write is applied on its own.
+//
+// The Storer methods can't return errors, so the first error of a
+// read or write is kept and returned by Err, and by Commit, which then
+// aborts the transaction. A read that fails reports the key as missing,
+// so the callers must check Err before trusting what they read.
 type KeyValueStore struct {
 	db     Databaser
 	prefix []byte
+	// txn is the transaction in progress, or nil if there is none.
+	txn Transaction
+	err error
 }
 
-func NewKeyValueStore(db Databaser, prefix string) KeyValueStore {
-	return KeyValueStore{
+func NewKeyValueStore(db Databaser, prefix string) *KeyValueStore {
+	return &KeyValueStore{
 		db:     db,
 		prefix: []byte(prefix),
 	}
 }
 
-func (k KeyValueStore) Delete(key []byte) {
-	err := k.db.Delete(append(k.prefix, key...))
-	if err != nil {
-		// notest
-		return
+// database returns the transaction in progress, if any, or the
+// database.
+func (k *KeyValueStore) database() Databaser {
+	if k.txn != nil {
+		return k.txn
+	}
+	return k.db
+}
+
+// key returns the given key with the prefix of the store.
+func (k *KeyValueStore) key(key []byte) []byte {
+	return append(append([]byte{}, k.prefix...), key...)
+}
+
+// setErr keeps the given error if it's the first one.
+func (k *KeyValueStore) setErr(err error) {
+	if err != nil && k.err == nil {
+		k.err = err
 	}
 }
 
-func (k KeyValueStore) Get(key []byte) ([]byte, bool) {
-	get, err := k.db.Get(append(k.prefix, key...))
+func (k *KeyValueStore) Delete(key []byte) {
+	k.setErr(k.database().Delete(k.key(key)))
+}
+
+func (k *KeyValueStore) Get(key []byte) ([]byte, bool) {
+	get, err := k.database().Get(k.key(key))
 	if err != nil {
-		// notest
+		k.setErr(err)
 		return nil, false
 	}
 	return get, get != nil
 }
 
-func (k KeyValueStore) Put(key, val []byte) {
-	err := k.db.Put(append(k.prefix, key...), val)
+func (k *KeyValueStore) Put(key, val []byte) {
+	k.setErr(k.database().Put(k.key(key), val))
+}
+
+// Err returns the first error of a read or write since the store was
+// created or since the last transaction started.
+func (k *KeyValueStore) Err() error {
+	return k.err
+}
+
+// Begin starts a write transaction and clears the error of the store.
+// The transaction is bound to the calling goroutine, which must end it
+// with Commit or Rollback, and blocks the writes of other goroutines
+// to the same database until then. The goroutine must not write to the
+// database other than through the store in the meantime.
+func (k *KeyValueStore) Begin() error {
+	if k.txn != nil {
+		return ErrTxnStarted
+	}
+	// mdbx write transactions must be used from the thread that started
+	// them.
+	runtime.LockOSThread()
+	txn, err := k.db.GetEnv().BeginTxn(nil, 0)
 	if err != nil {
 		// notest
-		return
+		runtime.UnlockOSThread()
+		return err
 	}
+	k.txn = &transaction{txn: txn, env: k.db.GetEnv()}
+	k.err = nil
+	return nil
 }
 
-func (k KeyValueStore) Begin() {
+// Commit applies the writes of the transaction in progress, or discards
+// them and returns the error if a read or write of the transaction
+// failed. Commit does nothing if there is no transaction in progress.
+func (k *KeyValueStore) Commit() error {
+	if k.txn == nil {
+		return nil
+	}
+	if k.err != nil {
+		k.Rollback()
+		return k.err
+	}
+	defer k.end()
+	if err := k.txn.Commit(); err != nil {
+		// notest
+		k.err = err
+		return err
+	}
+	return nil
+}
+
+// Rollback discards the writes of the transaction in progress. Rollback
+// does nothing if there is no transaction in progress, so it can be
+// deferred after Begin.
+func (k *KeyValueStore) Rollback() {
+	if k.txn == nil {
+		return
+	}
+	k.txn.Rollback()
+	k.end()
 }
 
-func (k KeyValueStore) Rollback() {
+// end releases the transaction and the thread bound to it.
+func (k *KeyValueStore) end() {
+	k.txn = nil
+	runtime.UnlockOSThread()
 }
 
-func (k KeyValueStore) Close() {
+func (k *KeyValueStore) Close() {
 	k.db.Close()
 }
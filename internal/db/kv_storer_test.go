@@ -5,7 +5,7 @@ import (
 )
 
 // setupTransactionDbTest creates a new TransactionDb for Tests
-func setupKvStoreTest(database Databaser) KeyValueStore {
+func setupKvStoreTest(database Databaser) *KeyValueStore {
 	return NewKeyValueStore(database, "test")
 }
 
@@ -13,7 +13,9 @@ func setupKvStoreTest(database Databaser) KeyValueStore {
 func TestKeyValueStoreNewDbAndCommit(t *testing.T) {
 	dbKV := NewKeyValueDb(t.TempDir(), 0)
 	database := setupKvStoreTest(dbKV)
-	database.Begin()
+	if err := database.Begin(); err != nil {
+		t.Fatalf("unexpected error starting the transaction: %s", err)
+	}
 
 	database.Put([]byte("key"), []byte("value"))
 
@@ -36,3 +38,83 @@ func TestKeyValueStoreNewDbAndCommit(t *testing.T) {
 
 	database.Close()
 }
+
+func TestKeyValueStore_Commit(t *testing.T) {
+	dbKV := NewKeyValueDb(t.TempDir(), 0)
+	t.Cleanup(dbKV.Close)
+	database := setupKvStoreTest(dbKV)
+	if err := database.Begin(); err != nil {
+		t.Fatalf("unexpected error starting the transaction: %s", err)
+	}
+	if err := database.Begin(); err != ErrTxnStarted {
+		t.Errorf("unexpected error %v starting a second transaction, want %v", err, ErrTxnStarted)
+	}
+	database.Put([]byte("key"), []byte("value"))
+	if err := database.Commit(); err != nil {
+		t.Fatalf("unexpected error committing the transaction: %s", err)
+	}
+
+	// The writes of a committed transaction are in the database.
+	get, err := dbKV.Get([]byte("testkey"))
+	if err != nil || string(get) != "value" {
+		t.Errorf("unexpected value %q, %v after commit, want %q", get, err, "value")
+	}
+	// Rollback does nothing after commit.
+	database.Rollback()
+	if _, has := database.Get([]byte("key")); !has {
+		t.Errorf("key removed by a rollback after commit")
+	}
+}
+
+func TestKeyValueStore_Rollback(t *testing.T) {
+	dbKV := NewKeyValueDb(t.TempDir(), 0)
+	t.Cleanup(dbKV.Close)
+	database := setupKvStoreTest(dbKV)
+	database.Put([]byte("kept"), []byte("value"))
+
+	if err := database.Begin(); err != nil {
+		t.Fatalf("unexpected error starting the transaction: %s", err)
+	}
+	database.Put([]byte("key"), []byte("value"))
+	database.Delete([]byte("kept"))
+	database.Rollback()
+
+	if _, has := database.Get([]byte("key")); has {
+		t.Errorf("key written by a rolled back transaction")
+	}
+	if _, has := database.Get([]byte("kept")); !has {
+		t.Errorf("key deleted by a rolled back transaction")
+	}
+}
+
+func TestKeyValueStore_Err(t *testing.T) {
+	dbKV := NewKeyValueDb(t.TempDir(), 0)
+	t.Cleanup(dbKV.Close)
+	database := setupKvStoreTest(dbKV)
+	if err := database.Begin(); err != nil {
+		t.Fatalf("unexpected error starting the transaction: %s", err)
+	}
+	database.Put([]byte("key"), []byte("value"))
+	// The key is longer than the maximum key size of the database.
+	database.Put(make([]byte, 4096), []byte("value"))
+	if database.Err() == nil {
+		t.Fatalf("expected an error writing a key too long")
+	}
+
+	// The transaction is discarded as a whole.
+	if err := database.Commit(); err == nil {
+		t.Errorf("expected an error committing a transaction with a failed write")
+	}
+	if _, has := database.Get([]byte("key")); has {
+		t.Errorf("key written by a failed transaction")
+	}
+
+	// A new transaction clears the error.
+	if err := database.Begin(); err != nil {
+		t.Fatalf("unexpected error starting the transaction: %s", err)
+	}
+	database.Put([]byte("key"), []byte("value"))
+	if err := database.Commit(); err != nil {
+		t.Errorf("unexpected error committing the transaction: %s", err)
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
-// GetCode returns the ContractCode associated with the given contract address.
-// If the contract code is not found, then nil is returned.
-func (x *Manager) GetCode(contractAddress []byte) *Code {
-	rawData, err := x.codeDatabase.Get(contractAddress)
+// GetCode returns the ContractCode associated with the given class hash. If
+// the contract code is not found, then nil is returned.
+func (x *Manager) GetCode(classHash []byte) *Code {
+	rawData, err := x.codeDatabase.Get(classHash)
 	if err != nil {
 		panic(any(fmt.Errorf("database error: %s", err)))
 	}
@@ -25,14 +25,15 @@ func (x *Manager) GetCode(contractAddress []byte) *Code {
 }
 
 // PutCode stores a new contract code into the database, associated with the
-// given contract address. If the contract address already have a contract code
-// in the database, then the value is updated.
-func (x *Manager) PutCode(contractAddress []byte, code *Code) {
+// given class hash. The contracts with the same class share the code, so if
+// the class hash already have a contract code in the database, then the value
+// is updated.
+func (x *Manager) PutCode(classHash []byte, code *Code) {
 	rawData, err := proto.Marshal(code)
 	if err != nil {
 		panic(any(fmt.Errorf("marshal error: %s", err)))
 	}
-	if err := x.codeDatabase.Put(contractAddress, rawData); err != nil {
+	if err := x.codeDatabase.Put(classHash, rawData); err != nil {
 		panic(any(fmt.Errorf("database error: %s", err)))
 	}
 }
@@ -10,11 +10,11 @@ import (
 )
 
 var codes = []struct {
-	Address []byte
-	Code    *Code
+	ClassHash []byte
+	Code      *Code
 }{
 	{
-		Address: decodeString("1bd7ca87f139693e6681be2042194cf631c4e8d77027bf0ea9e6d55fc6018ac"),
+		ClassHash: decodeString("1bd7ca87f139693e6681be2042194cf631c4e8d77027bf0ea9e6d55fc6018ac"),
 		Code: &Code{Code: [][]byte{
 			decodeString("40780017fff7fff"),
 			decodeString("1"),
@@ -29,11 +29,10 @@ var codes = []struct {
 
 func TestManager_Code(t *testing.T) {
 	codeDatabase := db.NewKeyValueDb(t.TempDir(), 0)
-	storageDatabase := db.NewBlockSpecificDatabase(db.NewKeyValueDb(t.TempDir(), 0))
-	manager := NewStateManager(codeDatabase, storageDatabase)
+	manager := NewStateManager(codeDatabase)
 	for _, code := range codes {
-		manager.PutCode(code.Address, code.Code)
-		obtainedCode := manager.GetCode(code.Address)
+		manager.PutCode(code.ClassHash, code.Code)
+		obtainedCode := manager.GetCode(code.ClassHash)
 		if !equalCodes(t, code.Code, obtainedCode) {
 			t.Errorf("Code are different afte Put-Get operation")
 		}
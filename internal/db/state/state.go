@@ -5,18 +5,16 @@ import (
 )
 
 // Manager is a database manager, with the objective of managing
-// the contract codes and contract storages databases.
+// the contract codes database.
 type Manager struct {
-	codeDatabase    db.Databaser
-	storageDatabase *db.BlockSpecificDatabase
+	codeDatabase db.Databaser
 }
 
-// NewStateManager returns a new instance of Manager with the given database sources.
-func NewStateManager(codeDatabase db.Databaser, storageDatabase *db.BlockSpecificDatabase) *Manager {
-	return &Manager{codeDatabase, storageDatabase}
+// NewStateManager returns a new instance of Manager with the given database source.
+func NewStateManager(codeDatabase db.Databaser) *Manager {
+	return &Manager{codeDatabase}
 }
 
 func (m *Manager) Close() {
 	m.codeDatabase.Close()
-	m.storageDatabase.Close()
 }
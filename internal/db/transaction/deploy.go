@@ -0,0 +1,38 @@
+package transaction
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// ErrInvalidContractAddress is matched by the errors returned when the
+// address of a deployed contract doesn't match the deploy transaction. Use
+// errors.Is to check for it.
+var ErrInvalidContractAddress = errors.New("invalid contract address")
+
+// VerifyContractAddress checks that the ContractAddress of the deploy
+// transaction is the address derived from its salt, class hash and
+// constructor calldata, as returned by feeder.ContractAddress with a zero
+// deployer address.
+func (x *Deploy) VerifyContractAddress() error {
+	calldata := make([]*big.Int, len(x.ConstructorCallData))
+	for i, data := range x.ConstructorCallData {
+		calldata[i] = new(big.Int).SetBytes(data)
+	}
+	address := feeder.ContractAddress(
+		new(big.Int),
+		new(big.Int).SetBytes(x.ContractAddressSalt),
+		new(big.Int).SetBytes(x.ClassHash),
+		calldata,
+	)
+	want := common.BigToFelt(address).Bytes()
+	if !bytes.Equal(common.BytesToFelt(x.ContractAddress).Bytes(), want) {
+		return fmt.Errorf("%w: contract deployed at %x, computed %x", ErrInvalidContractAddress, x.ContractAddress, want)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package transaction
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+func TestDeploy_VerifyContractAddress(t *testing.T) {
+	classHash := common.HexToFelt("0x10455c752b86932ce552f2b0fe81a880746649b9aee7e0d842bf3f52378f9f8")
+	address := feeder.ContractAddress(
+		new(big.Int), big.NewInt(0x5a17), classHash.Big(), []*big.Int{big.NewInt(1), big.NewInt(2)},
+	)
+	deploy := &Deploy{
+		ContractAddressSalt: []byte{0x5a, 0x17},
+		ConstructorCallData: [][]byte{{1}, {2}},
+		ContractAddress:     common.BigToFelt(address).Bytes(),
+		ClassHash:           classHash.Bytes(),
+	}
+	if err := deploy.VerifyContractAddress(); err != nil {
+		t.Errorf("unexpected error verifying the contract address: %s", err)
+	}
+
+	deploy.ContractAddressSalt = []byte{0x5a, 0x18}
+	if err := deploy.VerifyContractAddress(); !errors.Is(err, ErrInvalidContractAddress) {
+		t.Errorf("unexpected error %v verifying the contract address with another salt", err)
+	}
+}
@@ -58,6 +58,16 @@ func (m *Manager) GetTransaction(txHash []byte) *Transaction {
 	return tx
 }
 
+// DeleteTransaction removes the transaction associated with the given key. If
+// the key does not exist then nothing is done.
+func (m *Manager) DeleteTransaction(txHash []byte) {
+	err := m.database.Delete(buildTxKey(txHash))
+	if err != nil {
+		// notest
+		log.Default.With("error", err).Panic("database error")
+	}
+}
+
 // PutReceipt stores  new transactions receipts in the database. This method
 // does not check if the key already exists. In the case, that the key already
 // exists the value is overwritten.
@@ -98,6 +108,16 @@ func (m *Manager) GetReceipt(txHash []byte) *TransactionReceipt {
 	return tx
 }
 
+// DeleteReceipt removes the transaction receipt associated with the given key.
+// If the key does not exist then nothing is done.
+func (m *Manager) DeleteReceipt(txHash []byte) {
+	err := m.database.Delete(buildReceiptKey(txHash))
+	if err != nil {
+		// notest
+		log.Default.With("error", err).Panic("database error")
+	}
+}
+
 // Close closes the manager, specific the associated database.
 func (m *Manager) Close() {
 	m.database.Close()
@@ -104,6 +104,21 @@ func TestManager_GetTransaction(t *testing.T) {
 	manager.Close()
 }
 
+func TestManager_DeleteTransaction(t *testing.T) {
+	database := db.NewKeyValueDb(t.TempDir(), 0)
+	manager := NewManager(database)
+	for _, tx := range txs {
+		manager.PutTransaction(tx.Hash, tx)
+	}
+	for _, tx := range txs {
+		manager.DeleteTransaction(tx.Hash)
+		if manager.GetTransaction(tx.Hash) != nil {
+			t.Errorf("transaction found after Delete operation")
+		}
+	}
+	manager.Close()
+}
+
 func decodeString(s string) []byte {
 	x, _ := hex.DecodeString(s)
 	return x
@@ -178,6 +193,21 @@ func TestManager_GetReceipt(t *testing.T) {
 	manager.Close()
 }
 
+func TestManager_DeleteReceipt(t *testing.T) {
+	database := db.NewKeyValueDb(t.TempDir(), 0)
+	manager := NewManager(database)
+	for _, receipt := range receipts {
+		manager.PutReceipt(receipt.TxHash, receipt)
+	}
+	for _, receipt := range receipts {
+		manager.DeleteReceipt(receipt.TxHash)
+		if manager.GetReceipt(receipt.TxHash) != nil {
+			t.Errorf("receipt found after Delete operation")
+		}
+	}
+	manager.Close()
+}
+
 func equalMessage(t *testing.T, a, b proto.Message) bool {
 	aRaw, err := proto.Marshal(a)
 	if err != nil {
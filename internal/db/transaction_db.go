@@ -66,15 +66,14 @@ func (d *transaction) getOne(key []byte) (val []byte, err error) {
 		return nil, err
 	}
 	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
 	val, err = d.txn.Get(dbi, key)
 	if err != nil {
 		if mdbx.IsNotFound(err) {
-			err = nil
 			return nil, nil
 		}
 		return nil, err
 	}
-	runtime.UnlockOSThread()
 	return val, nil
 }
 
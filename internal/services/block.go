@@ -100,3 +100,16 @@ func (s *blockService) StoreBlock(blockHash []byte, block *block.Block) {
 
 	s.manager.PutBlock(blockHash, block)
 }
+
+// DeleteBlock removes the block with the given hash from the database, together
+// with its block number index entry if it points to this block.
+func (s *blockService) DeleteBlock(blockHash []byte) {
+	s.AddProcess()
+	defer s.DoneProcess()
+
+	s.logger.
+		With("blockHash", blockHash).
+		Debug("DeleteBlock")
+
+	s.manager.DeleteBlock(blockHash)
+}
@@ -16,12 +16,12 @@ type stateService struct {
 	manager *state.Manager
 }
 
-func (s *stateService) Setup(codeDatabase db.Databaser, storageDatabase *db.BlockSpecificDatabase) {
+func (s *stateService) Setup(codeDatabase db.Databaser) {
 	if s.Running() {
 		// notest
 		s.logger.Panic("service is already running")
 	}
-	s.manager = state.NewStateManager(codeDatabase, storageDatabase)
+	s.manager = state.NewStateManager(codeDatabase)
 }
 
 func (s *stateService) Run() error {
@@ -42,8 +42,7 @@ func (s *stateService) setDefaults() {
 	if s.manager == nil {
 		// notest
 		codeDatabase := db.NewKeyValueDb(config.DataDir+"/code", 0)
-		storageDatabase := db.NewBlockSpecificDatabase(db.NewKeyValueDb(config.DataDir+"/storage", 0))
-		s.manager = state.NewStateManager(codeDatabase, storageDatabase)
+		s.manager = state.NewStateManager(codeDatabase)
 	}
 }
 
@@ -52,64 +51,24 @@ func (s *stateService) Close(ctx context.Context) {
 	s.manager.Close()
 }
 
-func (s *stateService) StoreCode(contractAddress []byte, code *state.Code) {
+func (s *stateService) StoreCode(classHash []byte, code *state.Code) {
 	s.AddProcess()
 	defer s.DoneProcess()
 
 	s.logger.
-		With("contractAddress", contractAddress).
+		With("classHash", classHash).
 		Debug("StoreCode")
 
-	s.manager.PutCode(contractAddress, code)
+	s.manager.PutCode(classHash, code)
 }
 
-func (s *stateService) GetCode(contractAddress []byte) *state.Code {
+func (s *stateService) GetCode(classHash []byte) *state.Code {
 	s.AddProcess()
 	defer s.DoneProcess()
 
 	s.logger.
-		With("contractAddress", contractAddress).
+		With("classHash", classHash).
 		Debug("GetCode")
 
-	return s.manager.GetCode(contractAddress)
-}
-
-func (s *stateService) StoreStorage(contractAddress string, blockNumber uint64, storage *state.Storage) {
-	s.AddProcess()
-	defer s.DoneProcess()
-
-	s.logger.
-		With("contractAddress", contractAddress, "blockNumber", blockNumber).
-		Debug("StoreStorage")
-
-	s.manager.PutStorage(contractAddress, blockNumber, storage)
-}
-
-func (s *stateService) GetStorage(contractAddress string, blockNumber uint64) *state.Storage {
-	s.AddProcess()
-	defer s.DoneProcess()
-
-	s.logger.
-		With("contractAddress", contractAddress, "blockNumber", blockNumber).
-		Debug("GetStorage")
-
-	return s.manager.GetStorage(contractAddress, blockNumber)
-}
-
-func (s *stateService) UpdateStorage(contractAddress string, blockNumber uint64, storage *state.Storage) {
-	s.AddProcess()
-	defer s.DoneProcess()
-
-	s.logger.
-		With("contractAddress", contractAddress, "blockNumber", blockNumber).
-		Debug("UpdateStorage")
-
-	oldStorage := s.GetStorage(contractAddress, blockNumber)
-	if oldStorage == nil {
-		// notest
-		s.StoreStorage(contractAddress, blockNumber, storage)
-	} else {
-		oldStorage.Update(storage)
-		s.StoreStorage(contractAddress, blockNumber, oldStorage)
-	}
+	return s.manager.GetCode(classHash)
 }
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/NethermindEth/juno/internal/db"
+	"github.com/NethermindEth/juno/internal/db/abi"
+	"github.com/NethermindEth/juno/internal/db/state"
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	feederAbi "github.com/NethermindEth/juno/pkg/feeder/abi"
+	starknetState "github.com/NethermindEth/juno/pkg/state"
+)
+
+// stateDiffApplier applies the state diffs of the feeder gateway state updates
+// to the StarkNet state kept on its own database, so the state root of each
+// block can be verified, and stores the classes of the deployed contracts
+// using the StateService and the AbiService. The state is recorded as a
+// version at every block, so it can be read as it was at past blocks. The
+// writes to the state for a block, which include the class hash of the
+// deployed contracts, are made in a single database transaction, so a block
+// is either applied or reverted entirely or not at all, even if the process
+// stops midway.
+type stateDiffApplier struct {
+	// client is the feeder gateway client used to fetch the definition of
+	// the deployed contracts.
+	client *feeder.Client
+	// database stores the state and the applied state updates.
+	database db.Databaser
+	// store is the store of the state on the database, through which the
+	// state and the applied state updates are written in a transaction.
+	store *db.KeyValueStore
+	// state is the StarkNet state, made of the contract storage tries and
+	// the global state trie.
+	state *starknetState.State
+}
+
+// newStateDiffApplier returns a new stateDiffApplier that uses the given feeder
+// client and database, and keeps the given number of versions of the state, or
+// all of them if history is zero.
+func newStateDiffApplier(client *feeder.Client, database db.Databaser, history int) *stateDiffApplier {
+	store := db.NewKeyValueStore(database, "")
+	return &stateDiffApplier{
+		client:   client,
+		database: database,
+		store:    store,
+		state:    starknetState.NewWithPruning(store, history),
+	}
+}
+
+// Apply updates the state with the given state update, setting the class hash
+// of the deployed contracts, and, if the resulting state root matches the
+// NewRoot of the update, calls write with the store of the state database so
+// the caller can make its own writes in the same transaction, before it's
+// committed. The code and the ABI of the classes that were not stored yet are
+// stored keyed by class hash before the state is updated, after checking that
+// the class hash of their definition matches the contract hash of the state
+// diff; as they are only reached through the class hash of a contract, a
+// class stored for a block that is not applied is harmless. If the hashes or
+// the roots do not match, or the database fails, an error is returned and the
+// state is left unchanged. The state update is kept so the block can be
+// reverted later with the Revert method.
+func (a *stateDiffApplier) Apply(ctx context.Context, blockNumber uint64, update *feeder.StateUpdateResponse, write func(store *db.KeyValueStore)) (err error) {
+	classes, err := a.newClasses(ctx, blockNumber, update)
+	if err != nil {
+		return err
+	}
+	for classHash, class := range classes {
+		StateService.StoreCode(feltBytes(classHash), &state.Code{Code: feltsBytes(class.definition.Program.Data)})
+		AbiService.StoreAbi(classHash, class.abi)
+	}
+
+	if err := a.store.Begin(); err != nil {
+		// notest
+		return err
+	}
+	defer a.store.Rollback()
+	defer a.recoverStoreError(&err)
+	for _, contract := range update.StateDiff.DeployedContracts {
+		address := common.HexToFelt(contract.Address).Big()
+		a.state.SetClassHash(address, common.HexToFelt(contract.ContractHash).Big())
+	}
+	for contract, diffs := range update.StateDiff.StorageDiffs {
+		address := common.HexToFelt(contract).Big()
+		for _, diff := range diffs {
+			a.state.SetStorage(address, common.HexToFelt(diff.Key).Big(), common.HexToFelt(diff.Value).Big())
+		}
+	}
+
+	root, err := a.state.Commit(blockNumber)
+	// The result is meaningless if a read of the state failed.
+	if storeErr := a.store.Err(); storeErr != nil {
+		return fmt.Errorf("block %d: %w", blockNumber, storeErr)
+	}
+	if err != nil {
+		return fmt.Errorf("block %d: %w", blockNumber, err)
+	}
+	want := common.HexToFelt(update.NewRoot).Big()
+	if root.Cmp(want) != 0 {
+		// The deferred rollback discards the new version.
+		return fmt.Errorf("state root mismatch at block %d: got %x, want %x", blockNumber, root, want)
+	}
+
+	rawUpdate, err := json.Marshal(update)
+	if err != nil {
+		// notest
+		return err
+	}
+	a.store.Put(stateUpdateKey(blockNumber), rawUpdate)
+	write(a.store)
+	if err := a.store.Commit(); err != nil {
+		// notest
+		return fmt.Errorf("block %d: %w", blockNumber, err)
+	}
+	return nil
+}
+
+// Revert undoes the state update applied at the given block number, which must
+// be the latest one applied, bringing the state back to its version of the
+// previous block, and checks the resulting state root against the OldRoot of
+// the update. Then write is called with the store of the state database so
+// the caller can make its own writes in the same transaction, before it's
+// committed. The code and the ABI of the classes deployed in the block are
+// kept, as other contracts may share them. If the root does not match or the
+// database fails, an error is returned and the state is left unchanged.
+func (a *stateDiffApplier) Revert(blockNumber uint64, write func(store *db.KeyValueStore)) (err error) {
+	rawUpdate, err := a.database.Get(stateUpdateKey(blockNumber))
+	if err != nil {
+		// notest
+		return err
+	}
+	if rawUpdate == nil {
+		return fmt.Errorf("state update not found for block %d", blockNumber)
+	}
+	update := new(feeder.StateUpdateResponse)
+	if err := json.Unmarshal(rawUpdate, update); err != nil {
+		// notest
+		return err
+	}
+
+	if err := a.store.Begin(); err != nil {
+		// notest
+		return err
+	}
+	defer a.store.Rollback()
+	defer a.recoverStoreError(&err)
+	revertErr := a.state.Revert(blockNumber)
+	root := a.state.Root()
+	// The result is meaningless if a read of the state failed.
+	if storeErr := a.store.Err(); storeErr != nil {
+		// notest
+		return fmt.Errorf("reverting block %d: %w", blockNumber, storeErr)
+	}
+	if revertErr != nil {
+		return revertErr
+	}
+	want := common.HexToFelt(update.OldRoot).Big()
+	if root.Cmp(want) != 0 {
+		// notest
+		return fmt.Errorf("state root mismatch reverting block %d: got %x, want %x", blockNumber, root, want)
+	}
+	a.store.Delete(stateUpdateKey(blockNumber))
+	write(a.store)
+	if err := a.store.Commit(); err != nil {
+		// notest
+		return fmt.Errorf("reverting block %d: %w", blockNumber, err)
+	}
+	return nil
+}
+
+// recoverStoreError recovers from a panic of the state caused by a failed
+// read of the store, returning the error of the store in err instead. The
+// store reports the key of a failed read as missing, so the tries panic on
+// what looks to them like a missing node. Any other panic goes on.
+func (a *stateDiffApplier) recoverStoreError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	// notest
+	storeErr := a.store.Err()
+	if storeErr == nil {
+		panic(r)
+	}
+	*err = storeErr
+}
+
+// class is the definition of a contract class and its parsed ABI.
+type class struct {
+	definition *feeder.ContractDefinition
+	abi        *abi.Abi
+}
+
+// newClasses returns the classes of the contracts deployed by the given state
+// update whose code is not stored yet, keyed by the hex representation of
+// their class hash. The definition of each class is fetched from the feeder
+// gateway and its class hash is checked against the contract hash of the
+// state diff.
+func (a *stateDiffApplier) newClasses(ctx context.Context, blockNumber uint64, update *feeder.StateUpdateResponse) (map[string]*class, error) {
+	classes := make(map[string]*class)
+	for _, contract := range update.StateDiff.DeployedContracts {
+		contractHash := common.HexToFelt(contract.ContractHash).Big()
+		classHash := "0x" + contractHash.Text(16)
+		if _, ok := classes[classHash]; ok || StateService.GetCode(feltBytes(classHash)) != nil {
+			continue
+		}
+		definition, err := a.client.GetFullContract(ctx, contract.Address, feeder.BlockNumber(blockNumber))
+		if err != nil {
+			return nil, err
+		}
+		got, err := definition.ClassHash()
+		if err != nil {
+			return nil, err
+		}
+		if got.Cmp(contractHash) != 0 {
+			return nil, fmt.Errorf("class hash mismatch for contract %s at block %d: got %x, want %x",
+				contract.Address, blockNumber, got, contractHash)
+		}
+		contractAbi, err := feederAbiToDBAbi(definition.Abi)
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %w", contract.Address, err)
+		}
+		classes[classHash] = &class{definition: definition, abi: contractAbi}
+	}
+	return classes, nil
+}
+
+// feederAbiToDBAbi parses the ABI of a contract definition into the ABI
+// stored by the AbiService.
+func feederAbiToDBAbi(contractAbi feeder.ContractAbi) (*abi.Abi, error) {
+	out := new(abi.Abi)
+	if len(contractAbi) == 0 {
+		return out, nil
+	}
+	parsed, err := contractAbi.Parse()
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range parsed.Functions {
+		out.Functions = append(out.Functions, feederFunctionToDBFunction(f))
+	}
+	for _, e := range parsed.Events {
+		event := &abi.AbiEvent{Name: e.Name, Keys: e.Keys}
+		for _, data := range e.Data {
+			event.Data = append(event.Data, &abi.AbiEvent_Data{Name: data.Name, Type: data.Type})
+		}
+		out.Events = append(out.Events, event)
+	}
+	for _, s := range parsed.Structs {
+		st := &abi.Struct{Name: s.Name, Size: uint64(s.Size)}
+		for _, member := range s.Members {
+			st.Fields = append(st.Fields, &abi.Struct_Field{
+				Name:   member.Name,
+				Type:   member.Type,
+				Offset: uint32(member.Offset),
+			})
+		}
+		out.Structs = append(out.Structs, st)
+	}
+	for _, handler := range parsed.L1Handlers {
+		out.L1Handlers = append(out.L1Handlers, feederFunctionToDBFunction(handler.Function))
+	}
+	if parsed.Constructor != nil {
+		out.Constructor = feederFunctionToDBFunction(parsed.Constructor.Function)
+	}
+	return out, nil
+}
+
+func feederFunctionToDBFunction(f feederAbi.Function) *abi.Function {
+	function := &abi.Function{Name: f.Name}
+	for _, input := range f.Inputs {
+		function.Inputs = append(function.Inputs, &abi.Function_Input{Name: input.Name, Type: input.Type})
+	}
+	for _, output := range f.Outputs {
+		function.Outputs = append(function.Outputs, &abi.Function_Output{Name: output.Name, Type: output.Type})
+	}
+	return function
+}
+
+func stateUpdateKey(blockNumber uint64) []byte {
+	return []byte("state_update:" + strconv.FormatUint(blockNumber, 10))
+}
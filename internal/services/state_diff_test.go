@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/NethermindEth/juno/internal/db"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	starknetState "github.com/NethermindEth/juno/pkg/state"
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+func TestStateDiffApplier_RootMismatch(t *testing.T) {
+	database := db.NewKeyValueDb(t.TempDir(), 0)
+	t.Cleanup(database.Close)
+	// The contract 0 is deployed at block 0, so it can be updated at block 1
+	// without deploying it again.
+	applier := newStateDiffApplier(nil, database, 0)
+	classHash := big.NewInt(7)
+	applier.state.SetClassHash(new(big.Int), classHash)
+	genesisRoot, err := applier.state.Commit(0)
+	if err != nil {
+		t.Fatalf("unexpected error committing the genesis state: %s", err)
+	}
+	want := starknetState.New(store.New())
+	want.SetClassHash(new(big.Int), classHash)
+	if _, err := want.Commit(0); err != nil {
+		t.Fatalf("unexpected error committing the genesis state: %s", err)
+	}
+	want.SetStorage(new(big.Int), big.NewInt(1), big.NewInt(2))
+	wantRoot, _ := want.Commit(1)
+
+	// The caller writes the progress key in the transaction of the block.
+	write := func(store *db.KeyValueStore) {
+		store.Put([]byte("progress"), []byte{1})
+	}
+
+	update := &feeder.StateUpdateResponse{NewRoot: "0x1"}
+	update.StateDiff.StorageDiffs = map[string][]feeder.KV{"0x0": {{Key: "0x1", Value: "0x2"}}}
+	if err := applier.Apply(context.Background(), 1, update, write); err == nil {
+		t.Fatalf("expected an error applying a state update with the wrong root")
+	}
+	// None of the writes of the block are kept.
+	if root := applier.state.Root(); root.Cmp(genesisRoot) != 0 {
+		t.Errorf("unexpected root %x after a root mismatch, want %x", root, genesisRoot)
+	}
+	if _, ok := applier.state.At(1); ok {
+		t.Errorf("version 1 recorded after a root mismatch")
+	}
+	if raw, err := database.Get(stateUpdateKey(1)); err != nil || raw != nil {
+		t.Errorf("state update stored after a root mismatch")
+	}
+	if raw, err := database.Get([]byte("progress")); err != nil || raw != nil {
+		t.Errorf("progress stored after a root mismatch")
+	}
+
+	update.NewRoot = "0x" + wantRoot.Text(16)
+	if err := applier.Apply(context.Background(), 1, update, write); err != nil {
+		t.Fatalf("unexpected error applying the state update: %s", err)
+	}
+	if root := applier.state.Root(); root.Cmp(wantRoot) != 0 {
+		t.Errorf("unexpected root %x, want %x", root, wantRoot)
+	}
+	if raw, err := database.Get(stateUpdateKey(1)); err != nil || raw == nil {
+		t.Errorf("state update not stored")
+	}
+	if raw, err := database.Get([]byte("progress")); err != nil || raw == nil {
+		t.Errorf("progress not stored")
+	}
+}
+
+func TestStateDiffApplier_StoreError(t *testing.T) {
+	database := db.NewKeyValueDb(t.TempDir(), 0)
+	t.Cleanup(database.Close)
+	// Every key of the store is longer than the maximum key size of the
+	// database, so every read and write fails.
+	kvStore := db.NewKeyValueStore(database, strings.Repeat("x", 4096))
+	applier := &stateDiffApplier{database: database, store: kvStore, state: starknetState.New(kvStore)}
+
+	update := &feeder.StateUpdateResponse{NewRoot: "0x1"}
+	update.StateDiff.StorageDiffs = map[string][]feeder.KV{"0x0": {{Key: "0x1", Value: "0x2"}}}
+	wrote := false
+	err := applier.Apply(context.Background(), 0, update, func(*db.KeyValueStore) { wrote = true })
+	if err == nil || kvStore.Err() == nil || !errors.Is(err, kvStore.Err()) {
+		t.Errorf("unexpected error %v applying a state update with a failing store, want %v", err, kvStore.Err())
+	}
+	if wrote {
+		t.Errorf("caller writes made with a failing store")
+	}
+}
@@ -12,11 +12,11 @@ import (
 )
 
 var codes = []struct {
-	Address []byte
-	Code    *state.Code
+	ClassHash []byte
+	Code      *state.Code
 }{
 	{
-		Address: decodeString("1bd7ca87f139693e6681be2042194cf631c4e8d77027bf0ea9e6d55fc6018ac"),
+		ClassHash: decodeString("10455c752b86932ce552f2b0fe81a880746649b9aee7e0d842bf3f52378f9f8"),
 		Code: &state.Code{Code: [][]byte{
 			decodeString("40780017fff7fff"),
 			decodeString("1"),
@@ -31,8 +31,7 @@ var codes = []struct {
 
 func TestStateService_Code(t *testing.T) {
 	codeDatabase := db.NewKeyValueDb(t.TempDir(), 0)
-	storageDatabase := db.NewBlockSpecificDatabase(db.NewKeyValueDb(t.TempDir(), 0))
-	StateService.Setup(codeDatabase, storageDatabase)
+	StateService.Setup(codeDatabase)
 
 	err := StateService.Run()
 	if err != nil {
@@ -41,101 +40,14 @@ func TestStateService_Code(t *testing.T) {
 	defer StateService.Close(context.Background())
 
 	for _, code := range codes {
-		StateService.StoreCode(code.Address, code.Code)
-		obtainedCode := StateService.GetCode(code.Address)
+		StateService.StoreCode(code.ClassHash, code.Code)
+		obtainedCode := StateService.GetCode(code.ClassHash)
 		if !equalCodes(t, code.Code, obtainedCode) {
 			t.Errorf("Code are different afte Put-Get operation")
 		}
 	}
 }
 
-func TestService_Storage(t *testing.T) {
-	initialData := [...]struct {
-		Contract    string
-		Storage     state.Storage
-		BlockNumber uint64
-	}{
-		{
-			"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
-			state.Storage{Storage: map[string]string{
-				"5": "22b",
-				"5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5": "7e5",
-			}},
-			3,
-		},
-		{
-			"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
-			state.Storage{Storage: map[string]string{
-				"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620300": "4e7e989d58a17cd279eca440c5eaa829efb6f9967aaad89022acbe644c39b36",
-				"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620301": "453ae0c9610197b18b13645c44d3d0a407083d96562e8752aab3fab616cecb0",
-				"6cf6c2f36d36b08e591e4489e92ca882bb67b9c39a3afccf011972a8de467f0": "7ab344d88124307c07b56f6c59c12f4543e9c96398727854a322dea82c73240",
-			}},
-			5,
-		},
-	}
-	codeDatabase := db.NewKeyValueDb(t.TempDir(), 0)
-	storageDatabase := db.NewBlockSpecificDatabase(db.NewKeyValueDb(t.TempDir(), 0))
-	StateService.Setup(codeDatabase, storageDatabase)
-
-	err := StateService.Run()
-	if err != nil {
-		t.Errorf("error starting the service: %s", err)
-	}
-	defer StateService.Close(context.Background())
-
-	for _, data := range initialData {
-		// StateService.StoreStorage(data.Contract, data.BlockNumber, &data.Storage)
-		StateService.UpdateStorage(data.Contract, data.BlockNumber, &data.Storage)
-	}
-	tests := [...]struct {
-		Contract    string
-		BlockNumber uint64
-		Ok          bool
-		Checks      map[string]string
-	}{
-		{
-			"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
-			0,
-			false,
-			nil,
-		},
-		{
-			"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
-			3,
-			true,
-			map[string]string{
-				"5": "22b",
-			},
-		},
-		{
-			"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
-			10000,
-			true,
-			map[string]string{
-				"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620300": "4e7e989d58a17cd279eca440c5eaa829efb6f9967aaad89022acbe644c39b36",
-			},
-		},
-	}
-	for _, test := range tests {
-		obtainedStorage := StateService.GetStorage(test.Contract, test.BlockNumber)
-		if test.Ok && obtainedStorage == nil {
-			t.Errorf("storage of contract %s must not found for bloc %d", test.Contract, test.BlockNumber)
-		}
-		if obtainedStorage != nil && test.Checks != nil {
-			for checkKey, checkValue := range test.Checks {
-				resultValue, ok := obtainedStorage.Storage[checkKey]
-				if !ok {
-					t.Errorf("key %s not found", checkKey)
-				}
-				if resultValue != checkValue {
-					t.Errorf("unexpected key value after Put-Get operations. Contract: %s, Key: %s, Obtained value: %s, Want: %s",
-						test.Contract, checkKey, resultValue, resultValue)
-				}
-			}
-		}
-	}
-}
-
 func decodeString(s string) []byte {
 	x, _ := hex.DecodeString(s)
 	return x
@@ -0,0 +1,684 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/NethermindEth/juno/internal/config"
+	"github.com/NethermindEth/juno/internal/db"
+	"github.com/NethermindEth/juno/internal/db/block"
+	"github.com/NethermindEth/juno/internal/db/transaction"
+	"github.com/NethermindEth/juno/internal/log"
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/types"
+	starknetState "github.com/NethermindEth/juno/pkg/state"
+)
+
+// latestBlockSyncedKey is the key used to store the number of the latest block
+// that was completely synchronized.
+var latestBlockSyncedKey = []byte("latestBlockSynced")
+
+// pollInterval is the time to wait before asking the feeder gateway for a new
+// block once the head of the chain is reached, or after a failed request.
+const pollInterval = 5 * time.Second
+
+// defaultConcurrency is the number of blocks downloaded in parallel when no
+// other value is configured.
+const defaultConcurrency = 8
+
+// starknetConfig holds the parameters of the StarkNet protocol used to
+// compute the commitments of the blocks.
+var starknetConfig = feeder.StarknetGeneralConfig{
+	TxCommitmentTreeHeight:    feeder.CommitmentTreeHeight,
+	EventCommitmentTreeHeight: feeder.CommitmentTreeHeight,
+}
+
+// SyncService is a service that walks the chain from the genesis block using the
+// feeder gateway and stores every block, transaction and receipt using the
+// BlockService and TransactionService, and the classes of the deployed
+// contracts using the StateService and the AbiService. The StarkNet state is
+// kept on its own database. These services must be running before the
+// SyncService is started. Before using the service, it must be configured with
+// the Setup method; otherwise, the value will be the default. To stop the
+// service, call the Close method.
+var SyncService syncService
+
+type syncService struct {
+	service
+	// client is the feeder gateway client used to fetch the blocks.
+	client *feeder.Client
+	// database stores the synchronization progress and the state tries.
+	database db.Databaser
+	// concurrency is the number of blocks downloaded in parallel.
+	concurrency int
+	// trieHistory is the number of past roots kept by each state trie, or
+	// zero to keep them all.
+	trieHistory int
+	// chainID is the chain served by the feeder gateway.
+	chainID feeder.ChainID
+	// stateDiffs applies the state update of each block.
+	stateDiffs *stateDiffApplier
+	// pending is the pending block of the feeder gateway, built on top of the
+	// latest block synced. It's only kept in memory and is nil if there is
+	// no such block.
+	pending *feeder.StarknetBlock
+	// pendingMu protects the pending block.
+	pendingMu sync.RWMutex
+	// ctx is the context of the gateway requests, canceled when the service
+	// is closed so the in-flight requests are interrupted.
+	ctx context.Context
+	// cancel cancels ctx.
+	cancel context.CancelFunc
+	// done is closed by the synchronization loop when it ends.
+	done chan struct{}
+}
+
+// Setup is used to configure the service before it's started. The client param
+// is the feeder gateway client used to fetch the blocks, and the database param
+// is the database where the synchronization progress and the state tries will
+// be stored.
+func (s *syncService) Setup(client *feeder.Client, database db.Databaser) {
+	if s.Running() {
+		// notest
+		s.logger.Panic("trying to Setup with service running")
+	}
+	s.client = client
+	s.database = database
+}
+
+// SetConcurrency sets the number of blocks downloaded in parallel from the
+// feeder gateway. The blocks are always stored in order, no matter how many
+// are downloaded at the same time. If the value is not positive, the default
+// is used.
+func (s *syncService) SetConcurrency(concurrency int) {
+	if s.Running() {
+		// notest
+		s.logger.Panic("trying to SetConcurrency with service running")
+	}
+	s.concurrency = concurrency
+}
+
+// SetTrieHistory sets the number of past roots kept by the global state trie
+// and by each contract storage trie, so their state at those blocks can be
+// read. Older roots are pruned as new blocks are synchronized. Reverting a
+// block needs the roots of the previous block, so at least two are needed to
+// follow the reorganizations of the chain. If the value is not positive, every
+// root is kept.
+func (s *syncService) SetTrieHistory(history int) {
+	if s.Running() {
+		// notest
+		s.logger.Panic("trying to SetTrieHistory with service running")
+	}
+	s.trieHistory = history
+}
+
+// SetChainID sets the chain served by the feeder gateway. The hashes and the
+// deploy transactions of the blocks of the chain produced before StarkNet
+// 0.7.0 are not verified, see
+// feeder.ChainID.LegacyBlocks. If the value is empty, the testnet is used,
+// which has more legacy blocks than the mainnet.
+func (s *syncService) SetChainID(chainID feeder.ChainID) {
+	if s.Running() {
+		// notest
+		s.logger.Panic("trying to SetChainID with service running")
+	}
+	s.chainID = chainID
+}
+
+// ChainID returns the chain set with the SetChainID method, or the default
+// once the service is started.
+func (s *syncService) ChainID() feeder.ChainID {
+	return s.chainID
+}
+
+// Run starts the service. The synchronization is made in the background,
+// resuming from the latest block stored on a previous run. If the Setup method
+// is not called before, the default values are used.
+func (s *syncService) Run() error {
+	if s.logger == nil {
+		s.logger = log.Default.Named("Sync Service")
+	}
+
+	if err := s.service.Run(); err != nil {
+		// notest
+		return err
+	}
+
+	s.setDefaults()
+	s.stateDiffs = newStateDiffApplier(s.client, s.database, s.trieHistory)
+
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	go s.loop()
+	return nil
+}
+
+func (s *syncService) setDefaults() {
+	if s.client == nil {
+		// notest
+		s.client = feeder.NewClient(config.Runtime.Network, "/feeder_gateway", nil)
+	}
+	if s.database == nil {
+		// notest
+		s.database = db.NewKeyValueDb(config.DataDir+"/sync", 0)
+	}
+	if s.concurrency <= 0 {
+		s.concurrency = defaultConcurrency
+	}
+	if s.chainID == "" {
+		s.chainID = feeder.Testnet
+	}
+}
+
+// Close stops the service, canceling the in-flight gateway requests and
+// waiting for the block being synchronized to be stored, and closes the
+// database.
+func (s *syncService) Close(ctx context.Context) {
+	// The service may be closed when it's not running, like when the process
+	// is interrupted before it's started, or more than once.
+	if !s.Running() {
+		return
+	}
+	if s.cancel != nil {
+		s.cancel()
+		select {
+		case <-s.done:
+		case <-ctx.Done():
+			// notest
+			s.logger.Warn("Timeout waiting for the synchronization to stop")
+		}
+	}
+	s.setPending(nil)
+	s.service.Close(ctx)
+	s.database.Close()
+}
+
+// LatestBlockSynced returns the number of the latest block stored by the
+// service and true, or false if no block has been synchronized yet.
+func (s *syncService) LatestBlockSynced() (uint64, bool) {
+	s.AddProcess()
+	defer s.DoneProcess()
+
+	return s.latestBlockSynced()
+}
+
+// PendingBlock returns the pending block built on top of the latest block
+// synced, or nil if there is none. The returned block must not be modified.
+// Unlike the other methods, it may be called when the service is not running,
+// so it can be served by the RPC server without racing with the service
+// stopping.
+func (s *syncService) PendingBlock() *feeder.StarknetBlock {
+	s.pendingMu.RLock()
+	defer s.pendingMu.RUnlock()
+	return s.pending
+}
+
+// PendingTransactions returns the transactions of the pending block, or nil if
+// there is no pending block.
+func (s *syncService) PendingTransactions() []feeder.TxnSpecificInfo {
+	pending := s.PendingBlock()
+	if pending == nil {
+		return nil
+	}
+	return pending.Transactions
+}
+
+// StorageAt returns the value of the given storage key of the contract at the
+// given address as it was at the block with the given hash, or at the latest
+// block synced if blockHash is nil. False is returned if the block is not
+// synced or its state is not kept anymore (see SetTrieHistory). The state is
+// read while the service keeps synchronizing, so a version pruned while it's
+// being read makes the read panic.
+func (s *syncService) StorageAt(address, key, blockHash *big.Int) (*big.Int, bool) {
+	s.AddProcess()
+	defer s.DoneProcess()
+
+	latest, ok := s.latestBlockSynced()
+	if !ok {
+		return nil, false
+	}
+	blockNumber := latest
+	if blockHash != nil {
+		b := BlockService.GetBlockByHash(common.BigToFelt(blockHash).Bytes())
+		// A block after the latest block synced was left by a synchronization
+		// that did not complete, so it has no state.
+		if b == nil || b.BlockNumber > latest {
+			return nil, false
+		}
+		blockNumber = b.BlockNumber
+	}
+	store := db.NewKeyValueStore(s.database, "")
+	st, ok := starknetState.New(store).At(blockNumber)
+	if !ok {
+		return nil, false
+	}
+	value := st.GetStorage(address, key)
+	if err := store.Err(); err != nil {
+		// notest
+		s.logger.With("error", err).Panic("database error")
+	}
+	return value, true
+}
+
+// loop synchronizes the blocks until the service context is canceled. When
+// the head of the chain is reached, the loop keeps polling the feeder gateway
+// for new blocks.
+func (s *syncService) loop() {
+	defer close(s.done)
+
+	s.logger.With("blockNumber", s.nextBlock(), "concurrency", s.concurrency, "chainID", s.chainID).
+		Info("Starting synchronization")
+
+	for {
+		if s.ctx.Err() != nil {
+			return
+		}
+
+		// The next block is computed on each iteration because a chain
+		// reorganization moves the latest block synced backwards.
+		next := s.nextBlock()
+		s.removeUnsyncedBlock(next)
+		head, err := s.syncBlocks(next)
+		if err != nil && s.ctx.Err() == nil {
+			s.logger.With("blockNumber", s.nextBlock(), "error", err).Error("Failed to sync block")
+		}
+		if err == nil && !head {
+			// The chain was reorganized, so the synchronization continues
+			// right away from the common ancestor.
+			continue
+		}
+		if err == nil {
+			// The head of the chain is reached, so the pending block is
+			// refreshed while waiting for the next block.
+			if err := s.updatePending(); err != nil {
+				s.logger.With("error", err).Error("Failed to update the pending block")
+			}
+		}
+		// Wait before retrying or polling for a new block.
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// nextBlock returns the number of the block that must be synchronized next.
+func (s *syncService) nextBlock() uint64 {
+	if latest, ok := s.latestBlockSynced(); ok {
+		return latest + 1
+	}
+	return 0
+}
+
+// syncBlocks downloads the blocks starting from the given block number and
+// stores them in order until the head of the chain is reached or the chain is
+// reorganized. Returns true if the head of the chain is reached, or false if a
+// block does not follow the latest block synced, in which case the chain is
+// reorganized before returning.
+func (s *syncService) syncBlocks(from uint64) (bool, error) {
+	// Canceling the context stops the downloads of the blocks that will not
+	// be stored.
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	blocks := s.download(ctx, from)
+	for {
+		fetched, err := blocks.next(ctx)
+		if err != nil {
+			return false, err
+		}
+		if errors.Is(fetched.err, feeder.ErrNotFound) {
+			return true, nil
+		}
+		if fetched.err != nil {
+			return false, fetched.err
+		}
+		ok, err := s.syncBlock(fetched.number, fetched.block, fetched.update)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+}
+
+// syncBlock applies the state update of the given block and stores the block
+// together with its transactions and receipts. Returns false if the block
+// does not follow the latest block synced, in which case the chain is
+// reorganized before returning. The blocks whose hash doesn't commit to their
+// header, with a transaction whose hash doesn't match its content, or that
+// deploy a contract at an address that doesn't match the deploy transaction,
+// are rejected. The hashes, the transaction hashes and the deploy
+// transactions of the legacy blocks of the chain are not verified, nor the
+// deploy transactions
+// of the contracts missing from the state update, whose class hash is
+// unknown.
+func (s *syncService) syncBlock(blockNumber uint64, b *feeder.StarknetBlock, update *feeder.StateUpdateResponse) (bool, error) {
+	dbBlock := feederBlockToDBBlock(b)
+	dbBlock.OldRoot = feltBytes(update.OldRoot)
+	legacy := blockNumber < s.chainID.LegacyBlocks()
+	if !legacy {
+		if err := dbBlock.VerifyHash(); err != nil {
+			return false, err
+		}
+		// The block hash commits to the transaction hashes, which commit
+		// to the content of the transactions.
+		if err := feeder.VerifyTransactionHashes(b, s.chainID); err != nil {
+			return false, err
+		}
+	}
+	txs := feederTransactionsToDBTransactions(b, update)
+	for _, tx := range txs {
+		if deploy := tx.GetDeploy(); deploy != nil && deploy.ClassHash != nil && !legacy {
+			if err := deploy.VerifyContractAddress(); err != nil {
+				return false, fmt.Errorf("transaction %x: %w", tx.Hash, err)
+			}
+		}
+	}
+	if blockNumber > 0 {
+		parent := BlockService.GetBlockByNumber(blockNumber - 1)
+		if parent != nil && !bytes.Equal(parent.Hash, feltBytes(b.ParentBlockHash)) {
+			return false, s.reorg(blockNumber - 1)
+		}
+	}
+	// The block is stored once its state root is verified, before the
+	// transaction of the state, in which the progress is written, is
+	// committed. If the commit fails, the block is left stored after the
+	// latest block synced until the loop removes it or it's stored again.
+	err := s.stateDiffs.Apply(s.ctx, blockNumber, update, func(store *db.KeyValueStore) {
+		s.storeBlock(b, txs, dbBlock)
+		putLatestBlockSynced(store, blockNumber)
+	})
+	if err != nil {
+		return false, err
+	}
+	// The pending block either became this block or is outdated.
+	s.setPending(nil)
+	s.logger.With("blockNumber", blockNumber, "blockHash", b.BlockHash).Info("Block synced")
+	return true, nil
+}
+
+// reorg reverts every block from the given head down to the newest block that
+// is still part of the canonical chain served by the feeder gateway. The
+// blocks of the canonical branch are then synchronized by the main loop.
+func (s *syncService) reorg(head uint64) error {
+	ancestor, found := uint64(0), false
+	if head > 0 {
+		var err error
+		ancestor, found, err = s.commonAncestor(head - 1)
+		if err != nil {
+			return err
+		}
+	}
+	s.logger.With("head", head, "commonAncestor", ancestor, "found", found).Warn("Chain reorganization detected")
+
+	for {
+		latest, ok := s.latestBlockSynced()
+		if !ok || (found && latest <= ancestor) {
+			return nil
+		}
+		if err := s.revertBlock(latest); err != nil {
+			return err
+		}
+	}
+}
+
+// commonAncestor walks back from the given block number looking for the
+// newest stored block whose hash matches the hash of the block with the same
+// number on the feeder gateway. Returns false if not even the genesis block
+// matches.
+func (s *syncService) commonAncestor(from uint64) (uint64, bool, error) {
+	for blockNumber := from; ; blockNumber-- {
+		canonical, err := s.client.GetBlock(s.ctx, feeder.BlockNumber(blockNumber))
+		// A block that is not found anymore is not part of the canonical
+		// chain.
+		if err != nil && !errors.Is(err, feeder.ErrNotFound) {
+			return 0, false, err
+		}
+		stored := BlockService.GetBlockByNumber(blockNumber)
+		if err == nil && stored != nil && bytes.Equal(stored.Hash, feltBytes(canonical.BlockHash)) {
+			return blockNumber, true, nil
+		}
+		if blockNumber == 0 {
+			return 0, false, nil
+		}
+	}
+}
+
+// revertBlock undoes everything stored for the block with the given number,
+// which must be the latest block synced: its state update, its transactions
+// and receipts, and the block itself.
+func (s *syncService) revertBlock(blockNumber uint64) error {
+	b := BlockService.GetBlockByNumber(blockNumber)
+	if b == nil {
+		// notest
+		return fmt.Errorf("block %d not found", blockNumber)
+	}
+	// The progress is written in the transaction of the state. The block is
+	// only deleted once the transaction is committed, so it's still found if
+	// the revert fails and has to be retried. If the process stops before the
+	// block is deleted, the loop removes it as it's after the latest block
+	// synced.
+	err := s.stateDiffs.Revert(blockNumber, func(store *db.KeyValueStore) {
+		if blockNumber == 0 {
+			store.Delete(latestBlockSyncedKey)
+		} else {
+			putLatestBlockSynced(store, blockNumber-1)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	deleteBlock(b)
+	s.setPending(nil)
+	s.logger.With("blockNumber", blockNumber, "blockHash", common.BytesToFelt(b.Hash).Hex()).Info("Block reverted")
+	return nil
+}
+
+// removeUnsyncedBlock removes the block with the given number, which must be
+// the next block to synchronize, if it's stored. Such a block was stored by a
+// synchronization whose state transaction was not committed, or was not
+// deleted by a revert that stopped after its state transaction was committed.
+func (s *syncService) removeUnsyncedBlock(blockNumber uint64) {
+	b := BlockService.GetBlockByNumber(blockNumber)
+	if b == nil {
+		return
+	}
+	s.logger.With("blockNumber", blockNumber, "blockHash", common.BytesToFelt(b.Hash).Hex()).Warn("Removing block not synced")
+	deleteBlock(b)
+}
+
+// deleteBlock deletes the given block with its transactions and receipts.
+func deleteBlock(b *block.Block) {
+	for _, txHash := range b.TxHashes {
+		TransactionService.DeleteReceipt(txHash)
+		TransactionService.DeleteTransaction(txHash)
+	}
+	BlockService.DeleteBlock(b.Hash)
+}
+
+// updatePending fetches the pending block from the feeder gateway. The block
+// is only kept if it's built on top of the latest block synced; otherwise, the
+// pending block is cleared.
+func (s *syncService) updatePending() error {
+	b, err := s.client.GetBlock(s.ctx, feeder.PendingBlock)
+	if errors.Is(err, feeder.ErrNotFound) {
+		s.setPending(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var parent *block.Block
+	if latest, ok := s.latestBlockSynced(); ok {
+		parent = BlockService.GetBlockByNumber(latest)
+	}
+	if parent == nil || !bytes.Equal(parent.Hash, feltBytes(b.ParentBlockHash)) {
+		s.setPending(nil)
+		return nil
+	}
+	// The pending block has no number yet.
+	b.BlockNumber = types.BlockNumber(-1)
+	s.setPending(b)
+	return nil
+}
+
+func (s *syncService) setPending(b *feeder.StarknetBlock) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.pending = b
+}
+
+// storeBlock stores the transactions txs and the receipts of the given block
+// and then dbBlock, the block itself, so a stored block always has all its
+// transactions stored.
+func (s *syncService) storeBlock(b *feeder.StarknetBlock, txs []*transaction.Transaction, dbBlock *block.Block) {
+	for _, tx := range txs {
+		TransactionService.StoreTransaction(tx.Hash, tx)
+	}
+	status := transaction.Status(transaction.Status_value[string(b.Status)])
+	for _, receipt := range b.TransactionReceipts {
+		TransactionService.StoreReceipt(feltBytes(receipt.TransactionHash), feederReceiptToDBReceipt(&receipt, status))
+	}
+	BlockService.StoreBlock(dbBlock.Hash, dbBlock)
+}
+
+func (s *syncService) latestBlockSynced() (uint64, bool) {
+	value, err := s.database.Get(latestBlockSyncedKey)
+	if err != nil {
+		// notest
+		s.logger.With("error", err).Panic("database error")
+	}
+	if value == nil {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(value), true
+}
+
+// putLatestBlockSynced writes the number of the latest block synced to the
+// given store.
+func putLatestBlockSynced(store *db.KeyValueStore, blockNumber uint64) {
+	value := make([]byte, 8)
+	binary.BigEndian.PutUint64(value, blockNumber)
+	store.Put(latestBlockSyncedKey, value)
+}
+
+// feltBytes returns the 32 bytes representation of the field element encoded
+// in the given hex-string.
+func feltBytes(s string) []byte {
+	return common.HexToFelt(s).Bytes()
+}
+
+// feltsBytes returns the 32 bytes representation of each one of the field
+// elements encoded in the given hex-strings.
+func feltsBytes(s []string) [][]byte {
+	out := make([][]byte, len(s))
+	for i, x := range s {
+		out[i] = feltBytes(x)
+	}
+	return out
+}
+
+func feederBlockToDBBlock(b *feeder.StarknetBlock) *block.Block {
+	txHashes := make([][]byte, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		txHashes[i] = feltBytes(tx.TransactionHash)
+	}
+	eventCount := uint64(0)
+	for _, receipt := range b.TransactionReceipts {
+		eventCount += uint64(len(receipt.Events))
+	}
+	return &block.Block{
+		Hash:             feltBytes(b.BlockHash),
+		BlockNumber:      uint64(b.BlockNumber),
+		ParentBlockHash:  feltBytes(b.ParentBlockHash),
+		Status:           string(b.Status),
+		SequencerAddress: feltBytes(b.SequencerAddress),
+		GlobalStateRoot:  feltBytes(b.StateRoot),
+		TimeStamp:        b.Timestamp,
+		TxCount:          uint64(len(b.Transactions)),
+		TxCommitment:     common.BigToFelt(b.TransactionCommitment(starknetConfig)).Bytes(),
+		EventCount:       eventCount,
+		EventCommitment:  common.BigToFelt(b.EventCommitment(starknetConfig)).Bytes(),
+		TxHashes:         txHashes,
+	}
+}
+
+// feederTransactionsToDBTransactions returns the transactions of the given
+// block. The class hashes of the contracts deployed are taken from the state
+// update of the block.
+func feederTransactionsToDBTransactions(b *feeder.StarknetBlock, update *feeder.StateUpdateResponse) []*transaction.Transaction {
+	classHashes := make(map[common.Felt][]byte, len(update.StateDiff.DeployedContracts))
+	for _, contract := range update.StateDiff.DeployedContracts {
+		classHashes[common.HexToFelt(contract.Address)] = feltBytes(contract.ContractHash)
+	}
+	txs := make([]*transaction.Transaction, len(b.Transactions))
+	for i := range b.Transactions {
+		txs[i] = feederTransactionToDBTransaction(&b.Transactions[i], classHashes)
+	}
+	return txs
+}
+
+func feederTransactionToDBTransaction(tx *feeder.TxnSpecificInfo, classHashes map[common.Felt][]byte) *transaction.Transaction {
+	out := &transaction.Transaction{Hash: feltBytes(tx.TransactionHash)}
+	if tx.Type == "DEPLOY" {
+		out.Tx = &transaction.Transaction_Deploy{Deploy: &transaction.Deploy{
+			ContractAddressSalt: feltBytes(tx.ContractAddressSalt),
+			ConstructorCallData: feltsBytes(tx.ConstructorCalldata),
+			ContractAddress:     feltBytes(tx.ContractAddress),
+			ClassHash:           classHashes[common.HexToFelt(tx.ContractAddress)],
+		}}
+		return out
+	}
+	maxFee := new(big.Int)
+	if tx.MaxFee != "" {
+		maxFee = common.HexToFelt(tx.MaxFee).Big()
+	}
+	out.Tx = &transaction.Transaction_Invoke{Invoke: &transaction.InvokeFunction{
+		ContractAddress:    feltBytes(tx.ContractAddress),
+		EntryPointSelector: feltBytes(tx.EntryPointSelector),
+		CallData:           feltsBytes(tx.Calldata),
+		Signature:          feltsBytes(tx.Signature),
+		MaxFee:             maxFee.Bytes(),
+	}}
+	return out
+}
+
+func feederReceiptToDBReceipt(receipt *feeder.TransactionExecution, status transaction.Status) *transaction.TransactionReceipt {
+	messagesSent := make([]*transaction.MessageToL1, len(receipt.L2ToL1Messages))
+	for i, msg := range receipt.L2ToL1Messages {
+		messagesSent[i] = &transaction.MessageToL1{
+			ToAddress: common.FromHex(msg.ToAddress),
+			Payload:   feltsBytes(msg.Payload),
+		}
+	}
+	var l1OriginMessage *transaction.MessageToL2
+	if receipt.L1ToL2ConsumedMessage.FromAddress != "" {
+		l1OriginMessage = &transaction.MessageToL2{
+			FromAddress: receipt.L1ToL2ConsumedMessage.FromAddress,
+			Payload:     feltsBytes(receipt.L1ToL2ConsumedMessage.Payload),
+		}
+	}
+	events := make([]*transaction.Event, len(receipt.Events))
+	for i, event := range receipt.Events {
+		events[i] = &transaction.Event{
+			FromAddress: feltBytes(event.FromAddress),
+			Keys:        feltsBytes(event.Keys),
+			Data:        feltsBytes(event.Data),
+		}
+	}
+	return &transaction.TransactionReceipt{
+		TxHash:          feltBytes(receipt.TransactionHash),
+		ActualFee:       feltBytes(receipt.ActualFee),
+		Status:          status,
+		MessagesSent:    messagesSent,
+		L1OriginMessage: l1OriginMessage,
+		Events:          events,
+	}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// fetchedBlock is a block downloaded from the feeder gateway together with its
+// state update.
+type fetchedBlock struct {
+	number uint64
+	block  *feeder.StarknetBlock
+	update *feeder.StateUpdateResponse
+	// err is the error returned by the feeder gateway while downloading the
+	// block or its state update.
+	err error
+}
+
+// downloadJob is a request for a worker to download a block. The worker sends
+// the downloaded block to result.
+type downloadJob struct {
+	number uint64
+	result chan<- *fetchedBlock
+}
+
+// blockDownload is a download of consecutive blocks made by a pool of workers.
+// The blocks may be downloaded in any order, but they are returned in block
+// order by the next method.
+type blockDownload struct {
+	// results has a channel for each block that is being downloaded or was
+	// already downloaded, in block order. Its capacity bounds how far ahead of
+	// the block being stored the workers can go.
+	results chan (<-chan *fetchedBlock)
+}
+
+// download starts downloading the blocks from the given block number with as
+// many workers as the concurrency of the service. The download stops when the
+// given context is canceled.
+func (s *syncService) download(ctx context.Context, from uint64) *blockDownload {
+	d := &blockDownload{results: make(chan (<-chan *fetchedBlock), s.concurrency)}
+	jobs := make(chan downloadJob)
+	for i := 0; i < s.concurrency; i++ {
+		go func() {
+			for job := range jobs {
+				job.result <- s.fetchBlock(ctx, job.number)
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for blockNumber := from; ; blockNumber++ {
+			// The result is queued before the job is given to a worker, so
+			// the order of the results is the order of the blocks. Once the
+			// queue is full, no more blocks are downloaded until the oldest
+			// one is taken.
+			result := make(chan *fetchedBlock, 1)
+			select {
+			case d.results <- result:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- downloadJob{number: blockNumber, result: result}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return d
+}
+
+// next waits for the next block of the download. Returns an error if the
+// given context is canceled first.
+func (d *blockDownload) next(ctx context.Context) (*fetchedBlock, error) {
+	select {
+	case result := <-d.results:
+		select {
+		case fetched := <-result:
+			return fetched, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// fetchBlock downloads the block with the given number and its state update.
+func (s *syncService) fetchBlock(ctx context.Context, blockNumber uint64) *fetchedBlock {
+	fetched := &fetchedBlock{number: blockNumber}
+	fetched.block, fetched.err = s.client.GetBlock(ctx, feeder.BlockNumber(blockNumber))
+	if fetched.err != nil {
+		return fetched
+	}
+	fetched.update, fetched.err = s.client.GetStateUpdate(ctx, feeder.BlockNumber(blockNumber))
+	return fetched
+}
@@ -0,0 +1,861 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/internal/db"
+	"github.com/NethermindEth/juno/internal/db/block"
+	"github.com/NethermindEth/juno/internal/db/transaction"
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feederfakes"
+	"github.com/NethermindEth/juno/pkg/feeder/feedertest"
+	"github.com/NethermindEth/juno/pkg/feeder/types"
+	starknetState "github.com/NethermindEth/juno/pkg/state"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// feederBlocks are the blocks served by the fake feeder gateway used on the
+// SyncService tests. The index of each block is its block number. They are the
+// first blocks of the mainnet, with their real hashes: they predate StarkNet
+// 0.7.0, so their hashes are not verified by the default chain of the
+// SyncService, and only some of their transactions are kept. Neither are the
+// addresses of the contracts deployed by the genesis block, which predate the
+// class hashes of the contracts. The contracts are deployed with the class of
+// testContract, and the state roots are those of the resulting state.
+var feederBlocks = []feeder.StarknetBlock{
+	{
+		BlockHash:        "0x47c3637b57c2b079b93c61539950c17e868a28f46cdef28f88521067f21e943",
+		ParentBlockHash:  "0x0",
+		BlockNumber:      0,
+		SequencerAddress: "0x0",
+		StateRoot:        "059f355757bb7c997d9a55d91dcac933fcfc745c38082676b67a82604a0ac6b7",
+		Status:           "ACCEPTED_ON_L1",
+		Timestamp:        1637069048,
+		Transactions: []feeder.TxnSpecificInfo{
+			{
+				ContractAddress:     "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+				ContractAddressSalt: "0x546c86dc6e40a5e5492b782d8964e9a4274ff6ecb16d31eb09cee45a3564015",
+				ConstructorCalldata: []string{"0x1", "0x2"},
+				TransactionHash:     "0xe0a2e45a80bb827967e096bcf58874f6c01c191e0a0530624cba66a508ae75",
+				Type:                "DEPLOY",
+			},
+		},
+		TransactionReceipts: []feeder.TransactionExecution{
+			{
+				TransactionIndex: 0,
+				TransactionHash:  "0xe0a2e45a80bb827967e096bcf58874f6c01c191e0a0530624cba66a508ae75",
+			},
+		},
+	},
+	{
+		BlockHash:        "0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb",
+		ParentBlockHash:  "0x47c3637b57c2b079b93c61539950c17e868a28f46cdef28f88521067f21e943",
+		BlockNumber:      1,
+		SequencerAddress: "0x0",
+		StateRoot:        "0x3f04ffa63e188d602796505a2ee4f6e1f294ee29a914b057af8e75b17259d9f",
+		Status:           "ACCEPTED_ON_L1",
+		Timestamp:        1637072695,
+		Transactions: []feeder.TxnSpecificInfo{
+			{
+				ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+				EntryPointSelector: "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+				Calldata:           []string{"0x5", "0x22b"},
+				TransactionHash:    "0x12c96ae3c050771689eb261c9bf78fac2580708c7f1f3d69a9647d8be59f1e1",
+				Type:               "INVOKE_FUNCTION",
+			},
+		},
+		TransactionReceipts: []feeder.TransactionExecution{
+			{
+				TransactionIndex: 0,
+				TransactionHash:  "0x12c96ae3c050771689eb261c9bf78fac2580708c7f1f3d69a9647d8be59f1e1",
+				Events: []feeder.Event{
+					{
+						FromAddress: "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+						Keys:        []string{"0x1"},
+						Data:        []string{"0x22b"},
+					},
+				},
+			},
+		},
+	},
+}
+
+// feederStateUpdates are the state updates served by the fake feeder gateway
+// used on the SyncService tests. The index of each state update is its block
+// number.
+var feederStateUpdates = []string{
+	// See https://alpha-mainnet.starknet.io/feeder_gateway/get_state_update?blockNumber=0.
+	`{"block_hash": "0x47c3637b57c2b079b93c61539950c17e868a28f46cdef28f88521067f21e943", "new_root": "059f355757bb7c997d9a55d91dcac933fcfc745c38082676b67a82604a0ac6b7", "old_root": "0000000000000000000000000000000000000000000000000000000000000000", "state_diff": {"storage_diffs": {"0x735596016a37ee972c42adef6a3cf628c19bb3794369c65d2c82ba034aecf2c": [{"key": "0x5", "value": "0x64"}, {"key": "0x2f50710449a06a9fa789b3c029a63bd0b1f722f46505828a9f815cf91b31d8", "value": "0x2a222e62eabe91abdb6838fa8b267ffe81a6eb575f61e96ec9aa4460c0925a2"}], "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6": [{"key": "0x5", "value": "0x22b"}, {"key": "0x5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5", "value": "0x7e5"}, {"key": "0x313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620300", "value": "0x4e7e989d58a17cd279eca440c5eaa829efb6f9967aaad89022acbe644c39b36"}, {"key": "0x313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620301", "value": "0x453ae0c9610197b18b13645c44d3d0a407083d96562e8752aab3fab616cecb0"}, {"key": "0x6cf6c2f36d36b08e591e4489e92ca882bb67b9c39a3afccf011972a8de467f0", "value": "0x7ab344d88124307c07b56f6c59c12f4543e9c96398727854a322dea82c73240"}], "0x6ee3440b08a9c805305449ec7f7003f27e9f7e287b83610952ec36bdc5a6bae": [{"key": "0x1e2cd4b3588e8f6f9c4e89fb0e293bf92018c96d7a93ee367d29a284223b6ff", "value": "0x71d1e9d188c784a0bde95c1d508877a0d93e9102b37213d1e13f3ebc54a7751"}, {"key": "0x5f750dc13ed239fa6fc43ff6e10ae9125a33bd05ec034fc3bb4dd168df3505f", "value": "0x7e5"}, {"key": "0x48cba68d4e86764105adcdcf641ab67b581a55a4f367203647549c8bf1feea2", "value": "0x362d24a3b030998ac75e838955dfee19ec5b6eceb235b9bfbeccf51b6304d0b"}, {"key": "0x449908c349e90f81ab13042b1e49dc251eb6e3e51092d9a40f86859f7f415b0", "value": "0x6cb6104279e754967a721b52bcf5be525fdc11fa6db6ef5c3a4db832acf7804"}, {"key": "0x5bdaf1d47b176bfcd1114809af85a46b9c4376e87e361d86536f0288a284b65", "value": "0x28dff6722aa73281b2cf84cac09950b71fa90512db294d2042119abdd9f4b87"}, {"key": "0x5bdaf1d47b176bfcd1114809af85a46b9c4376e87e361d86536f0288a284b66", "value": "0x57a8f8a019ccab5bfc6ff86c96b1392257abb8d5d110c01d326b94247af161c"}], "0x31c887d82502ceb218c06ebb46198da3f7b92864a8223746bc836dda3e34b52": [{"key": "0x5f750dc13ed239fa6fc43ff6e10ae9125a33bd05ec034fc3bb4dd168df3505f", "value": "0x7c7"}, {"key": "0xdf28e613c065616a2e79ca72f9c1908e17b8c913972a9993da77588dc9cae9", "value": "0x1432126ac23c7028200e443169c2286f99cdb5a7bf22e607bcd724efa059040"}], "0x31c9cdb9b00cb35cf31c05855c0ec3ecf6f7952a1ce6e3c53c3455fcd75a280": [{"key": "0x5", "value": "0x65"}, {"key": "0x5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5", "value": "0x7c7"}, {"key": "0xcfc2e2866fd08bfb4ac73b70e0c136e326ae18fc797a2c090c8811c695577e", "value": "0x5f1dd5a5aef88e0498eeca4e7b2ea0fa7110608c11531278742f0b5499af4b3"}, {"key": "0x5fac6815fddf6af1ca5e592359862ede14f171e1544fd9e792288164097c35d", "value": "0x299e2f4b5a873e95e65eb03d31e532ea2cde43b498b50cd3161145db5542a5"}, {"key": "0x5fac6815fddf6af1ca5e592359862ede14f171e1544fd9e792288164097c35e", "value": "0x3d6897cf23da3bf4fd35cc7a43ccaf7c5eaf8f7c5b9031ac9b09a929204175f"}]}, "deployed_contracts": [{"address": "0x735596016a37ee972c42adef6a3cf628c19bb3794369c65d2c82ba034aecf2c", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}, {"address": "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}, {"address": "0x6ee3440b08a9c805305449ec7f7003f27e9f7e287b83610952ec36bdc5a6bae", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}, {"address": "0x31c887d82502ceb218c06ebb46198da3f7b92864a8223746bc836dda3e34b52", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}, {"address": "0x31c9cdb9b00cb35cf31c05855c0ec3ecf6f7952a1ce6e3c53c3455fcd75a280", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}]}}`,
+	`{"block_hash": "0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb", "new_root": "059f355757bb7c997d9a55d91dcac933fcfc745c38082676b67a82604a0ac6b7", "old_root": "059f355757bb7c997d9a55d91dcac933fcfc745c38082676b67a82604a0ac6b7", "state_diff": {"storage_diffs": {}, "deployed_contracts": []}}`,
+}
+
+// hashBlocks returns the given chain of blocks with the hash of each block
+// computed from its header, and the parent hash of each block but the first
+// one set to the hash of the previous block.
+func hashBlocks(blocks []feeder.StarknetBlock) []feeder.StarknetBlock {
+	hashed := make([]feeder.StarknetBlock, len(blocks))
+	for i, b := range blocks {
+		if i > 0 {
+			b.ParentBlockHash = hashed[i-1].BlockHash
+		}
+		b.BlockHash = "0x" + new(big.Int).SetBytes(feederBlockToDBBlock(&b).CalculateHash()).Text(16)
+		hashed[i] = b
+	}
+	return hashed
+}
+
+// testClassHash is the class hash of testContract.
+const testClassHash = "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"
+
+// testContract is the definition served for every contract deployed on the
+// fake feeder gateway.
+var testContract = []byte(`{
+	"abi": [
+		{"inputs": [{"name": "amount", "type": "felt"}], "name": "increase_balance", "outputs": [], "type": "function"},
+		{"inputs": [], "name": "get_balance", "outputs": [{"name": "res", "type": "felt"}], "stateMutability": "view", "type": "function"}
+	],
+	"entry_points_by_type": {
+		"CONSTRUCTOR": [],
+		"EXTERNAL": [
+			{"offset": "0x3a", "selector": "0x362398bec32bc0ebb411203221a35a0301193a96f317ebe5e40be9f60d15320"},
+			{"offset": "0x5b", "selector": "0x39e11d48192e4333233c7eb19d10ad67c362bb28580c604d67884c85da39695"}
+		],
+		"L1_HANDLER": []
+	},
+	"program": {
+		"builtins": ["pedersen", "range_check"],
+		"data": ["0x40780017fff7fff", "0x1", "0x208b7fff7fff7ffe"],
+		"debug_info": null,
+		"hints": {},
+		"identifiers": {},
+		"main_scope": "__main__",
+		"prime": "0x800000000000011000000000000000000000000000000000000000000000001",
+		"reference_manager": {"references": []}
+	}
+}`)
+
+// newFakeChain returns a chain for the fake feeder gateway with the given
+// blocks and state updates.
+func newFakeChain(t *testing.T, blocks []feeder.StarknetBlock, stateUpdates []string) *feedertest.Chain {
+	chain := feedertest.NewChain()
+	appendBlocks(t, chain, blocks, stateUpdates)
+	return chain
+}
+
+// appendBlocks appends the given blocks with their state updates to the chain.
+func appendBlocks(t *testing.T, chain *feedertest.Chain, blocks []feeder.StarknetBlock, stateUpdates []string) {
+	for i := range blocks {
+		b := blocks[i]
+		var update feeder.StateUpdateResponse
+		if err := json.Unmarshal([]byte(stateUpdates[i]), &update); err != nil {
+			t.Fatalf("unexpected error decoding the state update of block %d: %s", i, err)
+		}
+		chain.Append(&b, &update)
+		for _, contract := range update.StateDiff.DeployedContracts {
+			chain.SetFullContract(contract.Address, testContract)
+		}
+	}
+}
+
+// serve starts a fake feeder gateway with the given handler and returns a
+// client for it. The gateway is closed when the test ends.
+func serve(t *testing.T, handler http.Handler) *feeder.Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return feedertest.NewClient(server)
+}
+
+// waitForBlock waits until the SyncService stores the block with the given
+// block number, or fails the test after a timeout.
+func waitForBlock(t *testing.T, blockNumber uint64) {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		if latest, ok := SyncService.LatestBlockSynced(); ok && latest >= blockNumber {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for block %d to be synced", blockNumber)
+}
+
+// testChainID is the chain of the fake feeder gateway on the tests that need
+// the hash of every block to be verified, as it has no legacy blocks.
+const testChainID feeder.ChainID = "TEST"
+
+// setChainID sets the chain of the SyncService, which is set back to the
+// default when the test ends.
+func setChainID(t *testing.T, chainID feeder.ChainID) {
+	SyncService.SetChainID(chainID)
+	t.Cleanup(func() { SyncService.SetChainID("") })
+}
+
+// observeSyncErrors replaces the logger of the SyncService, until the test
+// ends, by one that records the errors logged.
+func observeSyncErrors(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	SyncService.logger = zap.New(core).Sugar()
+	t.Cleanup(func() { SyncService.logger = nil })
+	return logs
+}
+
+// waitForSyncError waits until the SyncService logs that it failed to sync a
+// block and returns the error logged, or fails the test after a timeout.
+func waitForSyncError(t *testing.T, logs *observer.ObservedLogs) error {
+	deadline := time.Now().Add(time.Minute)
+	for time.Now().Before(deadline) {
+		if entries := logs.FilterMessage("Failed to sync block").All(); len(entries) > 0 {
+			for _, field := range entries[0].Context {
+				if err, ok := field.Interface.(error); ok && field.Key == "error" {
+					return err
+				}
+			}
+			return nil
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for the synchronization to fail")
+	return nil
+}
+
+// hashTransactions returns the given transactions with the hash of each one
+// computed from its content in the chain of the tests.
+func hashTransactions(txs []feeder.TxnSpecificInfo) []feeder.TxnSpecificInfo {
+	hashed := make([]feeder.TxnSpecificInfo, len(txs))
+	for i, tx := range txs {
+		tx.TransactionHash = string(tx.CalculateHash(feeder.StarknetGeneralConfig{ChainID: testChainID}))
+		hashed[i] = tx
+	}
+	return hashed
+}
+
+// syncedState returns the StarkNet state stored by the SyncService on the
+// database in the given directory, which is closed when the test ends.
+func syncedState(t *testing.T, dir string) *starknetState.State {
+	database := db.NewKeyValueDb(dir, 0)
+	t.Cleanup(database.Close)
+	return newStateDiffApplier(nil, database, 0).state
+}
+
+// setupStorageServices runs the services used by the SyncService to store the
+// blocks, transactions, state and ABIs, closing them when the test ends.
+func setupStorageServices(t *testing.T) {
+	BlockService.Setup(db.NewKeyValueDb(t.TempDir(), 0))
+	TransactionService.Setup(db.NewKeyValueDb(t.TempDir(), 0))
+	if err := BlockService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the block service: %s", err)
+	}
+	t.Cleanup(func() { BlockService.Close(context.Background()) })
+	if err := TransactionService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the transaction service: %s", err)
+	}
+	t.Cleanup(func() { TransactionService.Close(context.Background()) })
+	StateService.Setup(db.NewKeyValueDb(t.TempDir(), 0))
+	if err := StateService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the state service: %s", err)
+	}
+	t.Cleanup(func() { StateService.Close(context.Background()) })
+	AbiService.Setup(db.NewKeyValueDb(t.TempDir(), 0))
+	if err := AbiService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the ABI service: %s", err)
+	}
+	t.Cleanup(func() { AbiService.Close(context.Background()) })
+}
+
+func TestSyncService(t *testing.T) {
+	setupStorageServices(t)
+
+	syncDir := t.TempDir()
+	chain := newFakeChain(t, feederBlocks[:1], feederStateUpdates[:1])
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 0)
+	SyncService.Close(context.Background())
+
+	// After a restart the synchronization must resume from the next block.
+	appendBlocks(t, chain, feederBlocks[1:], feederStateUpdates[1:])
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 1)
+	SyncService.Close(context.Background())
+	syncState := syncedState(t, syncDir)
+
+	t.Run("state", func(t *testing.T) {
+		address := "20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6"
+		if value := syncState.GetStorage(common.HexToFelt(address).Big(), big.NewInt(5)); value.Int64() != 0x22b {
+			t.Errorf("unexpected storage value %x at key 5, want 22b", value)
+		}
+		contract, ok := syncState.ContractState(common.HexToFelt(address).Big())
+		if !ok || contract.ClassHash.Cmp(common.HexToFelt(testClassHash).Big()) != 0 {
+			t.Fatalf("unexpected state %v of contract %s, want class hash %s", contract, address, testClassHash)
+		}
+		if StateService.GetCode(feltBytes(testClassHash)) == nil {
+			t.Errorf("code of class %s not found", testClassHash)
+		}
+		contractAbi := AbiService.GetAbi(testClassHash)
+		if contractAbi == nil {
+			t.Fatalf("ABI of class %s not found", testClassHash)
+		}
+		if len(contractAbi.Functions) != 2 || contractAbi.Functions[0].Name != "increase_balance" {
+			t.Errorf("unexpected functions %v of the ABI of class %s", contractAbi.Functions, testClassHash)
+		}
+	})
+
+	t.Run("state history", func(t *testing.T) {
+		for i, rawUpdate := range feederStateUpdates {
+			var update feeder.StateUpdateResponse
+			if err := json.Unmarshal([]byte(rawUpdate), &update); err != nil {
+				t.Fatalf("unexpected error decoding the state update of block %d: %s", i, err)
+			}
+			old, ok := syncState.At(uint64(i))
+			if !ok {
+				t.Errorf("state at block %d not found", i)
+				continue
+			}
+			if want := common.HexToFelt(update.NewRoot).Big(); old.Root().Cmp(want) != 0 {
+				t.Errorf("unexpected state root %x at block %d, want %x", old.Root(), i, want)
+			}
+		}
+	})
+
+	for _, b := range feederBlocks {
+		t.Run(fmt.Sprintf("block %d", b.BlockNumber), func(t *testing.T) {
+			stored := BlockService.GetBlockByNumber(uint64(b.BlockNumber))
+			if stored == nil {
+				t.Fatalf("block %d not found", b.BlockNumber)
+			}
+			if !bytes.Equal(stored.Hash, feltBytes(b.BlockHash)) {
+				t.Errorf("unexpected block hash %x, want %s", stored.Hash, b.BlockHash)
+			}
+			if stored.TxCount != uint64(len(b.Transactions)) {
+				t.Errorf("unexpected transaction count %d, want %d", stored.TxCount, len(b.Transactions))
+			}
+			if want := b.TransactionCommitment(starknetConfig); new(big.Int).SetBytes(stored.TxCommitment).Cmp(want) != 0 {
+				t.Errorf("unexpected transaction commitment %x, want %x", stored.TxCommitment, want)
+			}
+			if want := b.EventCommitment(starknetConfig); new(big.Int).SetBytes(stored.EventCommitment).Cmp(want) != 0 {
+				t.Errorf("unexpected event commitment %x, want %x", stored.EventCommitment, want)
+			}
+			for _, tx := range b.Transactions {
+				if TransactionService.GetTransaction(feltBytes(tx.TransactionHash)) == nil {
+					t.Errorf("transaction %s not found", tx.TransactionHash)
+				}
+			}
+			for _, receipt := range b.TransactionReceipts {
+				storedReceipt := TransactionService.GetReceipt(feltBytes(receipt.TransactionHash))
+				if storedReceipt == nil {
+					t.Errorf("receipt %s not found", receipt.TransactionHash)
+					continue
+				}
+				if len(storedReceipt.Events) != len(receipt.Events) {
+					t.Errorf("unexpected number of events %d, want %d", len(storedReceipt.Events), len(receipt.Events))
+				}
+			}
+		})
+	}
+}
+
+// Chains used to test the reorganizations. The genesis block deploys a single
+// contract; then the orphan block 1 updates its storage, while on the
+// canonical chain the block 1 leaves the state untouched.
+var (
+	reorgGenesis = hashBlocks([]feeder.StarknetBlock{{
+		ParentBlockHash: "0x0",
+		BlockNumber:     0,
+		StateRoot:       "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9",
+		Status:          "ACCEPTED_ON_L2",
+	}})[0]
+	reorgOrphanBlocks = hashBlocks([]feeder.StarknetBlock{
+		reorgGenesis,
+		{
+			BlockNumber: 1,
+			StateRoot:   "0x1b0e69d8ed4dc7fdb7894566d9ff8001d78e51a8713469d0b996fa6e42a71a5",
+			Status:      "ACCEPTED_ON_L2",
+			Transactions: []feeder.TxnSpecificInfo{
+				{
+					ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+					EntryPointSelector: "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+					Calldata:           []string{"0x5", "0x22c"},
+					TransactionHash:    "0xa11",
+					Type:               "INVOKE_FUNCTION",
+				},
+			},
+			TransactionReceipts: []feeder.TransactionExecution{{TransactionHash: "0xa11"}},
+		},
+	})
+	reorgOrphanStateUpdates = []string{
+		`{"block_hash": "0x1", "new_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "old_root": "0x0", "state_diff": {"storage_diffs": {"0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6": [{"key": "0x5", "value": "0x22b"}]}, "deployed_contracts": [{"address": "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6", "contract_hash": "0x50388d65a23f650cfba4aec925b15977dc450334377ca434d8e991becaf1e34"}]}}`,
+		`{"block_hash": "0xa1", "new_root": "0x1b0e69d8ed4dc7fdb7894566d9ff8001d78e51a8713469d0b996fa6e42a71a5", "old_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "state_diff": {"storage_diffs": {"0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6": [{"key": "0x5", "value": "0x22c"}]}, "deployed_contracts": []}}`,
+	}
+	reorgCanonicalBlocks = hashBlocks([]feeder.StarknetBlock{
+		reorgGenesis,
+		{
+			BlockNumber: 1,
+			StateRoot:   "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9",
+			Status:      "ACCEPTED_ON_L2",
+		},
+		{
+			BlockNumber: 2,
+			StateRoot:   "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9",
+			Status:      "ACCEPTED_ON_L2",
+		},
+	})
+	reorgCanonicalStateUpdates = []string{
+		reorgOrphanStateUpdates[0],
+		`{"block_hash": "0xb1", "new_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "old_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "state_diff": {"storage_diffs": {}, "deployed_contracts": []}}`,
+		`{"block_hash": "0xb2", "new_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "old_root": "0x5e124f7c809cc344744c359f53260f0bb86b0138eaeeeec19005506e0b397a9", "state_diff": {"storage_diffs": {}, "deployed_contracts": []}}`,
+	}
+)
+
+func TestSyncService_Reorg(t *testing.T) {
+	setupStorageServices(t)
+
+	syncDir := t.TempDir()
+	chain := newFakeChain(t, reorgOrphanBlocks, reorgOrphanStateUpdates)
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 1)
+	SyncService.Close(context.Background())
+
+	// The block 2 of the canonical chain does not follow the orphan block 1,
+	// so the orphan block must be reverted and the canonical one synced.
+	chain.Truncate(1)
+	appendBlocks(t, chain, reorgCanonicalBlocks[1:], reorgCanonicalStateUpdates[1:])
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 2)
+	SyncService.Close(context.Background())
+
+	if BlockService.GetBlockByHash(feltBytes(reorgOrphanBlocks[1].BlockHash)) != nil {
+		t.Errorf("orphan block found after the reorganization")
+	}
+	if TransactionService.GetTransaction(feltBytes("0xa11")) != nil {
+		t.Errorf("orphan transaction found after the reorganization")
+	}
+	if TransactionService.GetReceipt(feltBytes("0xa11")) != nil {
+		t.Errorf("orphan receipt found after the reorganization")
+	}
+	for _, b := range reorgCanonicalBlocks {
+		stored := BlockService.GetBlockByNumber(uint64(b.BlockNumber))
+		if stored == nil {
+			t.Errorf("block %d not found", b.BlockNumber)
+			continue
+		}
+		if !bytes.Equal(stored.Hash, feltBytes(b.BlockHash)) {
+			t.Errorf("unexpected hash %x for block %d, want %s", stored.Hash, b.BlockNumber, b.BlockHash)
+		}
+	}
+	address := common.HexToFelt("20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6").Big()
+	if value := syncedState(t, syncDir).GetStorage(address, big.NewInt(5)); value.Int64() != 0x22b {
+		t.Errorf("unexpected storage value %x at key 5, want 22b", value)
+	}
+}
+
+func TestSyncService_StorageAt(t *testing.T) {
+	setupStorageServices(t)
+
+	chain := newFakeChain(t, reorgOrphanBlocks, reorgOrphanStateUpdates)
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	defer SyncService.Close(context.Background())
+	waitForBlock(t, 1)
+
+	address := common.HexToFelt("20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6").Big()
+	tests := [...]struct {
+		blockHash *big.Int
+		want      int64
+	}{
+		{nil, 0x22c},
+		{common.HexToFelt(reorgOrphanBlocks[1].BlockHash).Big(), 0x22c},
+		{common.HexToFelt(reorgOrphanBlocks[0].BlockHash).Big(), 0x22b},
+	}
+	for _, test := range tests {
+		value, ok := SyncService.StorageAt(address, big.NewInt(5), test.blockHash)
+		if !ok || value.Int64() != test.want {
+			t.Errorf("unexpected storage value %v, %v at key 5 of block %v, want %x", value, ok, test.blockHash, test.want)
+		}
+	}
+	if value, ok := SyncService.StorageAt(address, big.NewInt(5), big.NewInt(0xb1)); ok {
+		t.Errorf("unexpected storage value %x at key 5 of an unknown block", value)
+	}
+}
+
+func TestSyncService_RemovesUnsyncedBlock(t *testing.T) {
+	setupStorageServices(t)
+
+	syncDir := t.TempDir()
+	chain := newFakeChain(t, reorgOrphanBlocks[:1], reorgOrphanStateUpdates[:1])
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 0)
+	SyncService.Close(context.Background())
+
+	// The orphan block 1 is stored but its state transaction was never
+	// committed, as if the process stopped in between.
+	orphan := reorgOrphanBlocks[1]
+	var update feeder.StateUpdateResponse
+	if err := json.Unmarshal([]byte(reorgOrphanStateUpdates[1]), &update); err != nil {
+		t.Fatalf("unexpected error decoding the state update: %s", err)
+	}
+	SyncService.storeBlock(&orphan, feederTransactionsToDBTransactions(&orphan, &update), feederBlockToDBBlock(&orphan))
+
+	appendBlocks(t, chain, reorgCanonicalBlocks[1:], reorgCanonicalStateUpdates[1:])
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 2)
+	SyncService.Close(context.Background())
+
+	if BlockService.GetBlockByHash(feltBytes(orphan.BlockHash)) != nil {
+		t.Errorf("block not synced found")
+	}
+	if TransactionService.GetTransaction(feltBytes("0xa11")) != nil {
+		t.Errorf("transaction of a block not synced found")
+	}
+	if TransactionService.GetReceipt(feltBytes("0xa11")) != nil {
+		t.Errorf("receipt of a block not synced found")
+	}
+	stored := BlockService.GetBlockByNumber(1)
+	if stored == nil || !bytes.Equal(stored.Hash, feltBytes(reorgCanonicalBlocks[1].BlockHash)) {
+		t.Errorf("unexpected block %v at number 1, want %s", stored, reorgCanonicalBlocks[1].BlockHash)
+	}
+}
+
+func TestSyncService_Pending(t *testing.T) {
+	setupStorageServices(t)
+
+	pending := &feeder.StarknetBlock{
+		ParentBlockHash: reorgGenesis.BlockHash,
+		Status:          "PENDING",
+		Transactions:    reorgOrphanBlocks[1].Transactions,
+	}
+	chain := newFakeChain(t, reorgOrphanBlocks[:1], reorgOrphanStateUpdates[:1])
+	chain.SetPending(pending)
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	defer SyncService.Close(context.Background())
+
+	deadline := time.Now().Add(time.Minute)
+	for SyncService.PendingBlock() == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for the pending block")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if b := SyncService.PendingBlock(); !b.BlockNumber.IsPending() {
+		t.Errorf("unexpected block number %d for the pending block", b.BlockNumber)
+	}
+	txs := SyncService.PendingTransactions()
+	if len(txs) != 1 || txs[0].TransactionHash != "0xa11" {
+		t.Errorf("unexpected pending transactions %+v", txs)
+	}
+
+	// Once the pending block becomes a numbered block, it's replaced.
+	chain.SetPending(nil)
+	appendBlocks(t, chain, reorgOrphanBlocks[1:], reorgOrphanStateUpdates[1:])
+	waitForBlock(t, 1)
+	if SyncService.PendingBlock() != nil {
+		t.Errorf("pending block found after it became a numbered block")
+	}
+	if TransactionService.GetTransaction(feltBytes("0xa11")) == nil {
+		t.Errorf("transaction 0xa11 not found")
+	}
+}
+
+func TestSyncService_CloseNotRunning(t *testing.T) {
+	setupStorageServices(t)
+
+	// A service closed before it's started.
+	var service syncService
+	service.Close(context.Background())
+
+	// A service closed twice.
+	SyncService.Setup(serve(t, feedertest.NewGateway(feedertest.NewChain())), db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	SyncService.Close(context.Background())
+	SyncService.Close(context.Background())
+}
+
+func TestSyncService_CloseCancelsRequests(t *testing.T) {
+	setupStorageServices(t)
+
+	httpClient := &feederfakes.FakeHttpClient{}
+	requested := make(chan struct{})
+	var once sync.Once
+	httpClient.DoStub = func(req *http.Request) (*http.Response, error) {
+		// Never answer, like a gateway that hangs.
+		once.Do(func() { close(requested) })
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	var p feeder.HttpClient = httpClient
+	SyncService.Setup(feeder.NewClient("https://local", "/feeder_gateway", &p), db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	<-requested
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	SyncService.Close(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("closing the service took %s while waiting for a gateway request", elapsed)
+	}
+}
+
+func TestSyncService_Concurrency(t *testing.T) {
+	setupStorageServices(t)
+
+	// A chain on top of the reorganization genesis block where the blocks
+	// leave the state untouched.
+	blocks := []feeder.StarknetBlock{reorgGenesis}
+	for i := 1; i < 20; i++ {
+		blocks = append(blocks, feeder.StarknetBlock{
+			BlockNumber: types.BlockNumber(i),
+			StateRoot:   reorgGenesis.StateRoot,
+			Status:      "ACCEPTED_ON_L2",
+		})
+	}
+	blocks = hashBlocks(blocks)
+	stateUpdates := []string{reorgOrphanStateUpdates[0]}
+	for _, b := range blocks[1:] {
+		stateUpdates = append(stateUpdates, fmt.Sprintf(
+			`{"block_hash": "%s", "new_root": "%s", "old_root": "%s", "state_diff": {"storage_diffs": {}, "deployed_contracts": []}}`,
+			b.BlockHash, b.StateRoot, b.StateRoot))
+	}
+	gateway := feedertest.NewGateway(newFakeChain(t, blocks, stateUpdates))
+
+	const concurrency = 4
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	client := serve(t, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// Only the downloads are limited by the concurrency, not the code
+		// requests made while storing a block.
+		if path.Base(req.URL.Path) == "get_code" {
+			gateway.ServeHTTP(w, req)
+			return
+		}
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+		// The lower the block number the slower the answer, so the blocks
+		// are downloaded out of order.
+		var blockNumber int
+		fmt.Sscan(req.URL.Query().Get("blockNumber"), &blockNumber)
+		time.Sleep(time.Duration(len(blocks)-blockNumber) * time.Millisecond)
+		gateway.ServeHTTP(w, req)
+	}))
+	SyncService.Setup(client, db.NewKeyValueDb(t.TempDir(), 0))
+	SyncService.SetConcurrency(concurrency)
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, uint64(len(blocks)-1))
+	SyncService.Close(context.Background())
+
+	if maxInFlight > concurrency {
+		t.Errorf("%d requests in flight, want at most %d", maxInFlight, concurrency)
+	}
+	if maxInFlight < 2 {
+		t.Errorf("blocks were not downloaded concurrently")
+	}
+	for _, b := range blocks {
+		stored := BlockService.GetBlockByNumber(uint64(b.BlockNumber))
+		if stored == nil {
+			t.Errorf("block %d not found", b.BlockNumber)
+			continue
+		}
+		if !bytes.Equal(stored.Hash, feltBytes(b.BlockHash)) {
+			t.Errorf("unexpected hash %x for block %d, want %s", stored.Hash, b.BlockNumber, b.BlockHash)
+		}
+	}
+}
+
+func TestSyncService_InvalidBlockHash(t *testing.T) {
+	setupStorageServices(t)
+
+	// The block 1 claims to be the orphan block, but its header was
+	// modified.
+	invalid := reorgOrphanBlocks[1]
+	invalid.Timestamp++
+	client := serve(t, feedertest.NewGateway(newFakeChain(t, []feeder.StarknetBlock{reorgGenesis, invalid}, reorgOrphanStateUpdates)))
+	setChainID(t, testChainID)
+	logs := observeSyncErrors(t)
+	syncDir := t.TempDir()
+	SyncService.Setup(client, db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	if err := waitForSyncError(t, logs); !errors.Is(err, block.ErrInvalidHash) {
+		t.Errorf("unexpected error %v, want %v", err, block.ErrInvalidHash)
+	}
+	if latest, _ := SyncService.LatestBlockSynced(); latest != 0 {
+		t.Errorf("unexpected latest block synced %d, want 0", latest)
+	}
+	SyncService.Close(context.Background())
+
+	if BlockService.GetBlockByNumber(1) != nil {
+		t.Errorf("block with an invalid hash found")
+	}
+	if _, ok := syncedState(t, syncDir).At(1); ok {
+		t.Errorf("the state update of the block with an invalid hash was applied")
+	}
+}
+
+func TestSyncService_InvalidTransactionHash(t *testing.T) {
+	setupStorageServices(t)
+
+	// The genesis block has an invoke transaction whose calldata was
+	// modified after it was hashed, while the hash of the block commits to
+	// the hash of the transaction.
+	genesis := reorgGenesis
+	genesis.Transactions = hashTransactions([]feeder.TxnSpecificInfo{{
+		ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+		EntryPointSelector: "0x362398bec32bc0ebb411203221a35a0301193a96f317ebe5e40be9f60d15320",
+		Calldata:           []string{"0x1"},
+		MaxFee:             "0x0",
+		Type:               "INVOKE_FUNCTION",
+	}})
+	genesis.Transactions[0].Calldata = []string{"0x2"}
+	client := serve(t, feedertest.NewGateway(newFakeChain(t, hashBlocks([]feeder.StarknetBlock{genesis}), reorgOrphanStateUpdates[:1])))
+	setChainID(t, testChainID)
+	logs := observeSyncErrors(t)
+	SyncService.Setup(client, db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	if err := waitForSyncError(t, logs); !errors.Is(err, feeder.ErrInvalidTransactionHash) {
+		t.Errorf("unexpected error %v, want %v", err, feeder.ErrInvalidTransactionHash)
+	}
+	if _, ok := SyncService.LatestBlockSynced(); ok {
+		t.Errorf("block with a tampered transaction synced")
+	}
+	SyncService.Close(context.Background())
+
+	if TransactionService.GetTransaction(feltBytes(genesis.Transactions[0].TransactionHash)) != nil {
+		t.Errorf("tampered transaction found")
+	}
+}
+
+func TestSyncService_InvalidContractAddress(t *testing.T) {
+	setupStorageServices(t)
+
+	// The genesis block deploys the contract 0x20cfa74..., but the deploy
+	// transaction derives a different address.
+	genesis := reorgGenesis
+	genesis.Transactions = hashTransactions([]feeder.TxnSpecificInfo{{
+		ContractAddress:     "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+		ContractAddressSalt: "0x1",
+		Type:                "DEPLOY",
+	}})
+	client := serve(t, feedertest.NewGateway(newFakeChain(t, hashBlocks([]feeder.StarknetBlock{genesis}), reorgOrphanStateUpdates[:1])))
+	setChainID(t, testChainID)
+	logs := observeSyncErrors(t)
+	SyncService.Setup(client, db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	if err := waitForSyncError(t, logs); !errors.Is(err, transaction.ErrInvalidContractAddress) {
+		t.Errorf("unexpected error %v, want %v", err, transaction.ErrInvalidContractAddress)
+	}
+	if _, ok := SyncService.LatestBlockSynced(); ok {
+		t.Errorf("block with an invalid deploy transaction synced")
+	}
+	SyncService.Close(context.Background())
+
+	if TransactionService.GetTransaction(feltBytes(genesis.Transactions[0].TransactionHash)) != nil {
+		t.Errorf("invalid deploy transaction found")
+	}
+}
+
+func TestSyncService_UnknownClassHash(t *testing.T) {
+	setupStorageServices(t)
+
+	// The genesis block deploys a contract missing from its state update, so
+	// its class hash is unknown and its address can't be verified.
+	genesis := reorgGenesis
+	genesis.Transactions = hashTransactions([]feeder.TxnSpecificInfo{{
+		ContractAddress:     "0x10",
+		ContractAddressSalt: "0x1",
+		Type:                "DEPLOY",
+	}})
+	client := serve(t, feedertest.NewGateway(newFakeChain(t, hashBlocks([]feeder.StarknetBlock{genesis}), reorgOrphanStateUpdates[:1])))
+	setChainID(t, testChainID)
+	SyncService.Setup(client, db.NewKeyValueDb(t.TempDir(), 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForBlock(t, 0)
+	SyncService.Close(context.Background())
+
+	if TransactionService.GetTransaction(feltBytes(genesis.Transactions[0].TransactionHash)) == nil {
+		t.Errorf("deploy transaction not found")
+	}
+}
+
+func TestSyncService_InvalidClassHash(t *testing.T) {
+	setupStorageServices(t)
+
+	// The genesis block deploys a contract of the class of testContract, but
+	// the definition served for it has a different bytecode.
+	chain := newFakeChain(t, reorgOrphanBlocks[:1], reorgOrphanStateUpdates[:1])
+	address := "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6"
+	chain.SetFullContract(address, bytes.Replace(testContract, []byte(`"0x1",`), []byte(`"0x2",`), 1))
+	logs := observeSyncErrors(t)
+	syncDir := t.TempDir()
+	SyncService.Setup(serve(t, feedertest.NewGateway(chain)), db.NewKeyValueDb(syncDir, 0))
+	if err := SyncService.Run(); err != nil {
+		t.Fatalf("unexpected error starting the sync service: %s", err)
+	}
+	waitForSyncError(t, logs)
+	if _, ok := SyncService.LatestBlockSynced(); ok {
+		t.Errorf("block deploying a contract with an invalid class hash synced")
+	}
+	SyncService.Close(context.Background())
+
+	if StateService.GetCode(feltBytes(testClassHash)) != nil {
+		t.Errorf("code of a contract with an invalid class hash found")
+	}
+	if _, ok := syncedState(t, syncDir).ContractState(common.HexToFelt(address).Big()); ok {
+		t.Errorf("contract with an invalid class hash found in the state")
+	}
+}
@@ -89,6 +89,19 @@ func (s *transactionService) StoreTransaction(txHash []byte, tx *transaction.Tra
 	s.manager.PutTransaction(txHash, tx)
 }
 
+// DeleteTransaction removes the transaction associated with the given
+// transaction hash from the database.
+func (s *transactionService) DeleteTransaction(txHash []byte) {
+	s.AddProcess()
+	defer s.DoneProcess()
+
+	s.logger.
+		With("txHash", txHash).
+		Debug("DeleteTransaction")
+
+	s.manager.DeleteTransaction(txHash)
+}
+
 // GetReceipt searches for the transaction receipt associated with the given
 // transaction hash. If the transaction does not exists on the database, then
 // returns nil.
@@ -111,3 +124,14 @@ func (s *transactionService) StoreReceipt(txHash []byte, receipt *transaction.Tr
 
 	s.manager.PutReceipt(txHash, receipt)
 }
+
+// DeleteReceipt removes the transaction receipt associated with the given
+// transaction hash from the database.
+func (s *transactionService) DeleteReceipt(txHash []byte) {
+	s.AddProcess()
+	defer s.DoneProcess()
+
+	s.logger.With("txHash", txHash).Debug("DeleteReceipt")
+
+	s.manager.DeleteReceipt(txHash)
+}
@@ -0,0 +1,52 @@
+package feeder
+
+import "strconv"
+
+// blockIDKind is the way a BlockID identifies a block.
+type blockIDKind int
+
+const (
+	latestBlockID blockIDKind = iota
+	pendingBlockID
+	hashBlockID
+	numberBlockID
+)
+
+// BlockID identifies a block on the gateway by its hash, by its number, or
+// with the latest or pending tags. The zero value identifies the latest block.
+type BlockID struct {
+	kind   blockIDKind
+	hash   string
+	number uint64
+}
+
+var (
+	// LatestBlock identifies the latest block accepted on the chain.
+	LatestBlock = BlockID{kind: latestBlockID}
+	// PendingBlock identifies the block being built by the sequencer.
+	PendingBlock = BlockID{kind: pendingBlockID}
+)
+
+// BlockHash returns the BlockID of the block with the given hash.
+func BlockHash(hash string) BlockID {
+	return BlockID{kind: hashBlockID, hash: hash}
+}
+
+// BlockNumber returns the BlockID of the block with the given number.
+func BlockNumber(number uint64) BlockID {
+	return BlockID{kind: numberBlockID, number: number}
+}
+
+// String returns the hash, the number, or the tag of the identified block.
+func (id BlockID) String() string {
+	switch id.kind {
+	case pendingBlockID:
+		return "pending"
+	case hashBlockID:
+		return id.hash
+	case numberBlockID:
+		return strconv.FormatUint(id.number, 10)
+	default:
+		return "latest"
+	}
+}
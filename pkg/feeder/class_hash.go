@@ -0,0 +1,187 @@
+package feeder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/NethermindEth/juno/pkg/crypto/keccak"
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// ClassHash returns the class hash of the contract definition:
+//
+//	h(api_version, h(external), h(l1_handler), h(constructor), h(builtins), hinted_class_hash, h(bytecode))
+//
+// where h is the Pedersen hash of an array, the API version is zero, the
+// entry points of each type are flattened as selector and offset, the
+// builtins are the ASCII encoding of their names and the hinted class hash is
+// the StarkNet Keccak of the JSON of the ABI and the program without the
+// debug information.
+func (d *ContractDefinition) ClassHash() (*big.Int, error) {
+	hinted, err := d.hintedClassHash()
+	if err != nil {
+		return nil, err
+	}
+	builtins := make([]*big.Int, len(d.Program.Builtins))
+	for i, builtin := range d.Program.Builtins {
+		builtins[i] = new(big.Int).SetBytes([]byte(builtin))
+	}
+	return pedersen.ArrayDigest(
+		new(big.Int),
+		entryPointsHash(d.EntryPointsByType.External),
+		entryPointsHash(d.EntryPointsByType.L1Handler),
+		entryPointsHash(d.EntryPointsByType.Constructor),
+		pedersen.ArrayDigest(builtins...),
+		hinted,
+		pedersen.ArrayDigest(feltsOf(d.Program.Data)...),
+	), nil
+}
+
+// entryPointsHash returns the hash of the selectors and offsets of the given
+// entry points.
+func entryPointsHash(entryPoints []EntryPoint) *big.Int {
+	data := make([]*big.Int, 0, 2*len(entryPoints))
+	for _, entryPoint := range entryPoints {
+		data = append(data, feltOf(entryPoint.Selector), feltOf(entryPoint.Offset))
+	}
+	return pedersen.ArrayDigest(data...)
+}
+
+// hintedClassHash returns the StarkNet Keccak of the ABI and the program of
+// the contract definition, encoded as cairo-lang does: a JSON object with
+// sorted keys, the separators of Python and only ASCII characters. The debug
+// information of the program is null, and the attributes of the program, as
+// well as their empty accessible scopes and flow tracking data, are left out
+// for the contracts compiled before they existed.
+func (d *ContractDefinition) hintedClassHash() (*big.Int, error) {
+	raw, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var class struct {
+		Abi     interface{}            `json:"abi"`
+		Program map[string]interface{} `json:"program"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&class); err != nil {
+		// notest
+		return nil, err
+	}
+
+	program := class.Program
+	if program == nil {
+		program = make(map[string]interface{})
+	}
+	program["debug_info"] = nil
+	if attributes, ok := program["attributes"].([]interface{}); ok && len(attributes) == 0 {
+		delete(program, "attributes")
+	} else if ok {
+		for _, attribute := range attributes {
+			attribute, ok := attribute.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if scopes, ok := attribute["accessible_scopes"].([]interface{}); ok && len(scopes) == 0 {
+				delete(attribute, "accessible_scopes")
+			}
+			if data, ok := attribute["flow_tracking_data"]; ok && data == nil {
+				delete(attribute, "flow_tracking_data")
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := encodePythonJSON(&buf, map[string]interface{}{"abi": class.Abi, "program": program}); err != nil {
+		return nil, err
+	}
+	return keccak.Digest250(buf.Bytes()), nil
+}
+
+// encodePythonJSON writes v, a value decoded from JSON with numbers as
+// json.Number, like Python's json.dumps with sort_keys=True does.
+func encodePythonJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if v {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		buf.WriteString(v.String())
+	case string:
+		encodePythonString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := encodePythonJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			encodePythonString(buf, key)
+			buf.WriteString(": ")
+			if err := encodePythonJSON(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		// notest
+		return fmt.Errorf("unexpected JSON value of type %T", v)
+	}
+	return nil
+}
+
+// encodePythonString writes s as a JSON string where every character out of
+// the printable ASCII range is escaped, like Python's json.dumps does.
+func encodePythonString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"':
+			buf.WriteString(`\"`)
+		case r == '\\':
+			buf.WriteString(`\\`)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == '\b':
+			buf.WriteString(`\b`)
+		case r == '\f':
+			buf.WriteString(`\f`)
+		case r >= ' ' && r <= '~':
+			buf.WriteRune(r)
+		case r > 0xffff:
+			r1, r2 := utf16.EncodeRune(r)
+			fmt.Fprintf(buf, `\u%04x\u%04x`, r1, r2)
+		default:
+			fmt.Fprintf(buf, `\u%04x`, r)
+		}
+	}
+	buf.WriteByte('"')
+}
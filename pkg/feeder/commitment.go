@@ -0,0 +1,68 @@
+package feeder
+
+import (
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+	"github.com/NethermindEth/juno/pkg/store"
+	"github.com/NethermindEth/juno/pkg/trie"
+)
+
+// CommitmentTreeHeight is the height of the Patricia tries of the transaction
+// and event commitments of the StarkNet blocks.
+const CommitmentTreeHeight = 64
+
+// TransactionCommitment returns the commitment of the transactions of the
+// block: the root of a Patricia trie of height
+// config.TxCommitmentTreeHeight where the key of each transaction is its
+// index in the block and the value is
+//
+//	h(transaction_hash, h(signature))
+//
+// where the signature of the transactions without one, like the deploy
+// ones, is empty.
+func (b *StarknetBlock) TransactionCommitment(config StarknetGeneralConfig) *big.Int {
+	leaves := make([]*big.Int, len(b.Transactions))
+	for i, tx := range b.Transactions {
+		leaves[i] = pedersen.Digest(feltOf(tx.TransactionHash), pedersen.ArrayDigest(feltsOf(tx.Signature)...))
+	}
+	return commitment(leaves, config.TxCommitmentTreeHeight)
+}
+
+// EventCommitment returns the commitment of the events emitted by the
+// transactions of the block: the root of a Patricia trie of height
+// config.EventCommitmentTreeHeight where the key of each event is its index
+// among all the events of the block, in the order of the receipts, and the
+// value is the hash of the event.
+func (b *StarknetBlock) EventCommitment(config StarknetGeneralConfig) *big.Int {
+	var leaves []*big.Int
+	for _, receipt := range b.TransactionReceipts {
+		for _, event := range receipt.Events {
+			leaves = append(leaves, event.hash())
+		}
+	}
+	return commitment(leaves, config.EventCommitmentTreeHeight)
+}
+
+// hash returns the hash of the event:
+//
+//	h(from_address, h(keys), h(data))
+func (e Event) hash() *big.Int {
+	return pedersen.ArrayDigest(
+		feltOf(e.FromAddress),
+		pedersen.ArrayDigest(feltsOf(e.Keys)...),
+		pedersen.ArrayDigest(feltsOf(e.Data)...),
+	)
+}
+
+// commitment returns the root of a Patricia trie of the given height where
+// the key of each leaf is its index.
+func commitment(leaves []*big.Int, height int) *big.Int {
+	t := trie.New(store.New(), height)
+	batch := t.NewBatch()
+	for i, leaf := range leaves {
+		batch.Put(big.NewInt(int64(i)), leaf)
+	}
+	batch.Commit()
+	return t.Commitment()
+}
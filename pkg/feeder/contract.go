@@ -0,0 +1,353 @@
+package feeder
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+
+	abi "github.com/NethermindEth/juno/pkg/feeder/abi"
+)
+
+// ContractDefinition represents the definition of a contract, as returned by
+// get_full_contract and sent in deploy transactions.
+//
+// The definition can be decoded and encoded back without losing anything: the
+// members of each object that don't have a field are kept, and are encoded
+// back together with the fields.
+type ContractDefinition struct {
+	Abi               ContractAbi       `json:"abi"`
+	EntryPointsByType EntryPointsByType `json:"entry_points_by_type"`
+	Program           Program           `json:"program"`
+
+	members object
+}
+
+// ContractAbi is the ABI of a contract. It's kept as it was encoded, so the
+// order of its entries is preserved; use Parse to get its functions, events,
+// structs, L1 handlers and constructor.
+type ContractAbi json.RawMessage
+
+// EntryPointsByType represents the entry points of a contract, grouped by
+// type.
+type EntryPointsByType struct {
+	Constructor []EntryPoint `json:"CONSTRUCTOR"`
+	External    []EntryPoint `json:"EXTERNAL"`
+	L1Handler   []EntryPoint `json:"L1_HANDLER"`
+
+	members object
+}
+
+// EntryPoint represents an entry point of a contract.
+type EntryPoint struct {
+	// Offset is the hex-encoded offset of the entry point in the program.
+	Offset string `json:"offset"`
+	// Selector is the hex-encoded selector of the entry point.
+	Selector string `json:"selector"`
+
+	members object
+}
+
+// Program represents the compiled Cairo program of a contract.
+type Program struct {
+	Builtins []string `json:"builtins"`
+	// Data is the bytecode of the program, as hex-encoded field elements.
+	Data []string `json:"data"`
+	// Hints are the hints of the program by pc.
+	Hints map[string][]Hint `json:"hints"`
+	// Identifiers are the identifiers of the program by full name.
+	Identifiers      map[string]Identifier `json:"identifiers"`
+	MainScope        string                `json:"main_scope"`
+	Prime            string                `json:"prime"`
+	ReferenceManager ReferenceManager      `json:"reference_manager"`
+
+	members object
+}
+
+// Hint represents a hint of a Cairo program.
+type Hint struct {
+	AccessibleScopes []string         `json:"accessible_scopes"`
+	Code             string           `json:"code"`
+	FlowTrackingData FlowTrackingData `json:"flow_tracking_data"`
+
+	members object
+}
+
+// FlowTrackingData represents the state of the ap register and the
+// references available at some point of a Cairo program.
+type FlowTrackingData struct {
+	ApTracking   ApTracking        `json:"ap_tracking"`
+	ReferenceIds map[string]uint64 `json:"reference_ids"`
+
+	members object
+}
+
+// ApTracking represents the tracking of the ap register.
+type ApTracking struct {
+	Group  uint64 `json:"group"`
+	Offset uint64 `json:"offset"`
+
+	members object
+}
+
+// Identifier represents an identifier of a Cairo program. Which fields are
+// set depends on the type of the identifier, like "function", "const",
+// "struct", "member", "alias" or "reference".
+type Identifier struct {
+	Type        string            `json:"type"`
+	Decorators  []string          `json:"decorators"`
+	Pc          uint64            `json:"pc"`
+	Size        uint64            `json:"size"`
+	Members     map[string]Member `json:"members"`
+	Value       *big.Int          `json:"value"`
+	Destination string            `json:"destination"`
+	CairoType   string            `json:"cairo_type"`
+	FullName    string            `json:"full_name"`
+	References  []Reference       `json:"references"`
+
+	members object
+}
+
+// Member represents a member of a struct identifier.
+type Member struct {
+	CairoType string `json:"cairo_type"`
+	Offset    uint64 `json:"offset"`
+
+	members object
+}
+
+// ReferenceManager holds the references of a Cairo program.
+type ReferenceManager struct {
+	References []Reference `json:"references"`
+
+	members object
+}
+
+// Reference represents a reference of a Cairo program.
+type Reference struct {
+	ApTrackingData ApTracking `json:"ap_tracking_data"`
+	Pc             uint64     `json:"pc"`
+	Value          string     `json:"value"`
+
+	members object
+}
+
+// Parse returns the parsed ABI.
+func (a ContractAbi) Parse() (*abi.Abi, error) {
+	var parsed abi.Abi
+	if err := parsed.UnmarshalAbiJSON(a); err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a ContractAbi) MarshalJSON() ([]byte, error) {
+	if a == nil {
+		return []byte("null"), nil
+	}
+	return a, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (a *ContractAbi) UnmarshalJSON(data []byte) error {
+	*a = append((*a)[:0], data...)
+	return nil
+}
+
+// The json.Marshaler and json.Unmarshaler implementations of the types of a
+// contract definition keep the members without a field. The aliases don't
+// have the methods, so they are encoded and decoded field by field.
+
+func (d ContractDefinition) MarshalJSON() ([]byte, error) {
+	type alias ContractDefinition
+	return d.members.encode((*alias)(&d))
+}
+
+func (d *ContractDefinition) UnmarshalJSON(data []byte) error {
+	type alias ContractDefinition
+	return d.members.decode(data, (*alias)(d))
+}
+
+func (e EntryPointsByType) MarshalJSON() ([]byte, error) {
+	type alias EntryPointsByType
+	return e.members.encode((*alias)(&e))
+}
+
+func (e *EntryPointsByType) UnmarshalJSON(data []byte) error {
+	type alias EntryPointsByType
+	return e.members.decode(data, (*alias)(e))
+}
+
+func (e EntryPoint) MarshalJSON() ([]byte, error) {
+	type alias EntryPoint
+	return e.members.encode((*alias)(&e))
+}
+
+func (e *EntryPoint) UnmarshalJSON(data []byte) error {
+	type alias EntryPoint
+	return e.members.decode(data, (*alias)(e))
+}
+
+func (p Program) MarshalJSON() ([]byte, error) {
+	type alias Program
+	return p.members.encode((*alias)(&p))
+}
+
+func (p *Program) UnmarshalJSON(data []byte) error {
+	type alias Program
+	return p.members.decode(data, (*alias)(p))
+}
+
+func (h Hint) MarshalJSON() ([]byte, error) {
+	type alias Hint
+	return h.members.encode((*alias)(&h))
+}
+
+func (h *Hint) UnmarshalJSON(data []byte) error {
+	type alias Hint
+	return h.members.decode(data, (*alias)(h))
+}
+
+func (f FlowTrackingData) MarshalJSON() ([]byte, error) {
+	type alias FlowTrackingData
+	return f.members.encode((*alias)(&f))
+}
+
+func (f *FlowTrackingData) UnmarshalJSON(data []byte) error {
+	type alias FlowTrackingData
+	return f.members.decode(data, (*alias)(f))
+}
+
+func (a ApTracking) MarshalJSON() ([]byte, error) {
+	type alias ApTracking
+	return a.members.encode((*alias)(&a))
+}
+
+func (a *ApTracking) UnmarshalJSON(data []byte) error {
+	type alias ApTracking
+	return a.members.decode(data, (*alias)(a))
+}
+
+func (i Identifier) MarshalJSON() ([]byte, error) {
+	type alias Identifier
+	return i.members.encode((*alias)(&i))
+}
+
+func (i *Identifier) UnmarshalJSON(data []byte) error {
+	type alias Identifier
+	return i.members.decode(data, (*alias)(i))
+}
+
+func (m Member) MarshalJSON() ([]byte, error) {
+	type alias Member
+	return m.members.encode((*alias)(&m))
+}
+
+func (m *Member) UnmarshalJSON(data []byte) error {
+	type alias Member
+	return m.members.decode(data, (*alias)(m))
+}
+
+func (r ReferenceManager) MarshalJSON() ([]byte, error) {
+	type alias ReferenceManager
+	return r.members.encode((*alias)(&r))
+}
+
+func (r *ReferenceManager) UnmarshalJSON(data []byte) error {
+	type alias ReferenceManager
+	return r.members.decode(data, (*alias)(r))
+}
+
+func (r Reference) MarshalJSON() ([]byte, error) {
+	type alias Reference
+	return r.members.encode((*alias)(&r))
+}
+
+func (r *Reference) UnmarshalJSON(data []byte) error {
+	type alias Reference
+	return r.members.decode(data, (*alias)(r))
+}
+
+// object keeps what a struct doesn't hold of the JSON object it was decoded
+// from: the members without a field, and which fields had a member, so the
+// struct can be encoded back to the same object.
+type object struct {
+	extra   map[string]json.RawMessage
+	present map[string]bool
+}
+
+// decode decodes the JSON object in data into v, a pointer to a struct, and
+// keeps the members without a field.
+func (o *object) decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(data, &members); err != nil {
+		return err
+	}
+	present := make(map[string]bool, len(members))
+	for _, field := range jsonFields(reflect.TypeOf(v).Elem()) {
+		if _, ok := members[field.name]; ok {
+			present[field.name] = true
+			delete(members, field.name)
+		}
+	}
+	o.present = present
+	o.extra = nil
+	if len(members) > 0 {
+		o.extra = members
+	}
+	return nil
+}
+
+// encode encodes v, a pointer to a struct, as a JSON object that includes the
+// members kept when it was decoded. The fields with a zero value are only
+// encoded if the object had a member for them.
+func (o object) encode(v interface{}) ([]byte, error) {
+	value := reflect.ValueOf(v).Elem()
+	members := make(map[string]json.RawMessage, len(o.extra)+value.NumField())
+	for name, member := range o.extra {
+		members[name] = member
+	}
+	for _, field := range jsonFields(value.Type()) {
+		fieldValue := value.Field(field.index)
+		if fieldValue.IsZero() && !o.present[field.name] {
+			continue
+		}
+		member, err := json.Marshal(fieldValue.Interface())
+		if err != nil {
+			return nil, err
+		}
+		members[field.name] = member
+	}
+	return json.Marshal(members)
+}
+
+// jsonField is an exported field of a struct and the name of its JSON
+// member.
+type jsonField struct {
+	index int
+	name  string
+}
+
+// jsonFields returns the exported fields of the given struct type.
+func jsonFields(t reflect.Type) []jsonField {
+	fields := make([]jsonField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{index: i, name: name})
+	}
+	return fields
+}
@@ -0,0 +1,38 @@
+package feeder
+
+import (
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// contractAddressPrefix is the ASCII encoding of "STARKNET_CONTRACT_ADDRESS",
+// the prefix of the hash of the contract addresses.
+var contractAddressPrefix = new(big.Int).SetBytes([]byte("STARKNET_CONTRACT_ADDRESS"))
+
+// contractAddressBound is the upper bound of the contract addresses,
+// 2²⁵¹ - 256.
+var contractAddressBound = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 251), big.NewInt(256))
+
+// ContractAddress returns the address of the contract with the given class
+// hash deployed by deployerAddress with the given salt and constructor
+// calldata:
+//
+//	h(prefix, deployer_address, salt, class_hash, h(constructor_calldata)) mod (2²⁵¹ - 256)
+//
+// where h is the Pedersen hash of an array and prefix is the ASCII encoding
+// of "STARKNET_CONTRACT_ADDRESS". The deployer address of the contracts
+// deployed with a deploy transaction is zero.
+//
+// The address doesn't depend on the state, so it can be computed before the
+// contract is deployed.
+func ContractAddress(deployerAddress, salt, classHash *big.Int, constructorCalldata []*big.Int) *big.Int {
+	address := pedersen.ArrayDigest(
+		contractAddressPrefix,
+		deployerAddress,
+		salt,
+		classHash,
+		pedersen.ArrayDigest(constructorCalldata...),
+	)
+	return address.Mod(address, contractAddressBound)
+}
@@ -0,0 +1,81 @@
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	// ErrNotFound is matched by the errors returned when the requested
+	// object (block, transaction, contract, ...) does not exist on the
+	// gateway. Use errors.Is to check for it.
+	ErrNotFound = errors.New("not found")
+	// ErrTransient is matched by the errors returned when the request failed
+	// for a reason that may not happen again, like a network failure, a rate
+	// limit or a gateway internal error. Use errors.Is to check for it.
+	ErrTransient = errors.New("transient failure")
+)
+
+// Error is the error returned when the gateway answers with an unsuccessful
+// status code.
+type Error struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the StarkNet error code of the response, like
+	// "StarknetErrorCode.BLOCK_NOT_FOUND". It's empty if the body of the
+	// response is not a StarkNet error.
+	Code string `json:"code"`
+	// Message is the message of the StarkNet error.
+	Message string `json:"message"`
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("feeder gateway error: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("feeder gateway error: status %d, code %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Is reports whether the error matches ErrNotFound or ErrTransient. An
+// object not found is never transient, whatever the status code.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.notFound()
+	case ErrTransient:
+		return !e.notFound() && isTransientStatus(e.StatusCode)
+	}
+	return false
+}
+
+// notFound reports whether the requested object does not exist.
+func (e *Error) notFound() bool {
+	return e.StatusCode == http.StatusNotFound || strings.HasSuffix(e.Code, "NOT_FOUND")
+}
+
+// transientError wraps the errors of the requests that failed before getting
+// a response from the gateway.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string {
+	return e.err.Error()
+}
+
+func (e *transientError) Unwrap() error {
+	return e.err
+}
+
+func (e *transientError) Is(target error) bool {
+	return target == ErrTransient
+}
+
+// isTransientStatus returns true if a response with the given status code
+// must be retried.
+func isTransientStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
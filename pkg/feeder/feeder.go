@@ -4,7 +4,9 @@ package feeder
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"net/url"
@@ -19,38 +21,62 @@ type HttpClient interface {
 	Do(*http.Request) (*http.Response, error)
 }
 
+const (
+	// defaultTimeout is the timeout of each attempt of a request made by the
+	// default http client.
+	defaultTimeout = 50 * time.Second
+	// defaultRate is the number of requests per second allowed by the
+	// default rate limiter.
+	defaultRate = 10
+	// defaultBurst is the maximum burst of requests allowed by the default
+	// rate limiter.
+	defaultBurst = 20
+)
+
 // Client represents a client for the StarkNet feeder gateway.
 type Client struct {
 	httpClient *HttpClient
 
 	BaseURL            *url.URL
 	BaseAPI, UserAgent string
+	// Retry is the policy used to retry the requests that fail with a
+	// transient error.
+	Retry RetryPolicy
+	// RateLimiter limits the rate of the requests sent to the gateway. If
+	// nil, the requests are not limited.
+	RateLimiter *RateLimiter
 }
 
-// NewClient returns a new Client.
+// NewClient returns a new Client. The client retries the failed requests using
+// the DefaultRetryPolicy and limits the rate of requests to the gateway; both
+// can be changed using the Retry and RateLimiter fields.
 func NewClient(baseURL, baseAPI string, client *HttpClient) *Client {
 	u, err := url.Parse(baseURL)
 	errpkg.CheckFatal(err, "Bad base URL.")
 	if client == nil {
 		var p HttpClient
 		c := http.Client{
-			Timeout: 50 * time.Second,
+			Timeout: defaultTimeout,
 		}
 		p = &c
 		client = &p
 	}
-	return &Client{BaseURL: u, BaseAPI: baseAPI, httpClient: client}
+	return &Client{
+		BaseURL:     u,
+		BaseAPI:     baseAPI,
+		httpClient:  client,
+		Retry:       DefaultRetryPolicy,
+		RateLimiter: NewRateLimiter(defaultRate, defaultBurst),
+	}
 }
 
-func formattedBlockIdentifier(blockHash, blockNumber string) map[string]string {
-	if len(blockHash) == 0 && len(blockNumber) == 0 {
-		// notest
-		return nil
-	}
-	if len(blockHash) == 0 {
-		return map[string]string{"blockNumber": blockNumber}
+// formattedBlockIdentifier returns the query params that identify the given
+// block on the gateway.
+func formattedBlockIdentifier(block BlockID) map[string]string {
+	if block.kind == hashBlockID {
+		return map[string]string{"blockHash": block.String()}
 	}
-	return map[string]string{"blockHash": blockHash}
+	return map[string]string{"blockNumber": block.String()}
 }
 
 func TxnIdentifier(txHash, txId string) map[string]string {
@@ -67,7 +93,7 @@ func TxnIdentifier(txHash, txId string) map[string]string {
 
 // newRequest creates a new request based on params and returns an
 // error otherwise.
-func (c *Client) newRequest(method, path string, query map[string]string, body any) (*http.Request, error) {
+func (c *Client) newRequest(ctx context.Context, method, path string, query map[string]string, body any) (*http.Request, error) {
 	rel := &url.URL{Path: c.BaseAPI + path}
 	u := c.BaseURL.ResolveReference(rel)
 	var buf io.ReadWriter
@@ -78,7 +104,7 @@ func (c *Client) newRequest(method, path string, query map[string]string, body a
 			return nil, err
 		}
 	}
-	req, err := http.NewRequest(method, u.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), buf)
 	if err != nil {
 		return nil, err
 	}
@@ -98,12 +124,62 @@ func (c *Client) newRequest(method, path string, query map[string]string, body a
 	return req, nil
 }
 
-// do executes a request and waits for response and returns an error
-// otherwise.
-func (c *Client) do(req *http.Request, v any) (*http.Response, error) {
+// send executes a request, retrying it while it fails with a transient error,
+// and returns the body of the successful response. If the gateway answers
+// with an unsuccessful status code, an *Error is returned.
+func (c *Client) send(req *http.Request) (*http.Response, []byte, error) {
+	return c.sendRetrying(req, isTransient)
+}
+
+// isTransient reports whether the attempt failed with a transient error.
+func isTransient(_ *http.Response, err error) bool {
+	return errors.Is(err, ErrTransient)
+}
+
+// sendRetrying is like send but retries the failed attempts for which retry
+// returns true, given the response, if any, and the error of the attempt.
+func (c *Client) sendRetrying(req *http.Request, retry func(*http.Response, error) bool) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		res, body, err := c.attempt(req)
+		if err == nil || !retry(res, err) || attempt >= c.Retry.MaxRetries {
+			return res, body, err
+		}
+		wait, ok := retryAfter(res)
+		if !ok {
+			wait = c.Retry.backoff(attempt)
+		}
+		log.Default.With("Error", err, "Retry", attempt+1, "Wait", wait).
+			Warn("Retrying request to the gateway.")
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				// notest
+				return nil, nil, err
+			}
+		}
+	}
+}
+
+// attempt executes a request once and returns the body of the response.
+func (c *Client) attempt(req *http.Request) (*http.Response, []byte, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(req.Context()); err != nil {
+			return nil, nil, err
+		}
+	}
 	res, err := (*c.httpClient).Do(req)
 	if err != nil {
-		return nil, err
+		// A canceled request must not be retried.
+		if ctxErr := req.Context().Err(); ctxErr != nil {
+			return nil, nil, ctxErr
+		}
+		return nil, nil, &transientError{err: err}
 	}
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
@@ -116,7 +192,42 @@ func (c *Client) do(req *http.Request, v any) (*http.Response, error) {
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
 		log.Default.With("Error", err).Debug("Error reading response.")
-		return nil, err
+		return res, nil, &transientError{err: err}
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		gatewayErr := &Error{StatusCode: res.StatusCode}
+		// The body is a StarkNet error most of the time, but it may be
+		// anything else for errors of the proxies in front of the gateway.
+		_ = json.Unmarshal(b, gatewayErr)
+		return res, b, gatewayErr
+	}
+	return res, b, nil
+}
+
+// logRequestError logs the error of a request to the gateway. The objects
+// not found are expected, for instance when polling for the next block, so
+// they are logged at the debug level.
+func (c *Client) logRequestError(err error) {
+	logger := log.Default.With("Error", err, "Gateway URL", c.BaseURL)
+	if errors.Is(err, ErrNotFound) {
+		logger.Debug("Object not found on the gateway.")
+		return
+	}
+	logger.Error("Error connecting to the gateway.")
+}
+
+// do executes a request and waits for response and returns an error
+// otherwise.
+func (c *Client) do(req *http.Request, v any) (*http.Response, error) {
+	return c.doRetrying(req, v, isTransient)
+}
+
+// doRetrying is like do but retries the failed attempts for which retry
+// returns true, as sendRetrying does.
+func (c *Client) doRetrying(req *http.Request, v any, retry func(*http.Response, error) bool) (*http.Response, error) {
+	res, b, err := c.sendRetrying(req, retry)
+	if err != nil {
+		return res, err
 	}
 	err = json.Unmarshal(b, v)
 	return res, err
@@ -125,21 +236,8 @@ func (c *Client) do(req *http.Request, v any) (*http.Response, error) {
 // doCodeWithABI executes a request and waits for response and returns an error
 // otherwise. de-Marshals response into appropriate ByteCode and ABI structs.
 func (c *Client) doCodeWithABI(req *http.Request, v *CodeInfo) (*http.Response, error) {
-	res, err := (*c.httpClient).Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer func(Body io.ReadCloser) {
-		err := Body.Close()
-		if err != nil {
-			// notest
-			log.Default.With("Error", err).Error("Error closing body of response.")
-			return
-		}
-	}(res.Body)
-	b, err := io.ReadAll(res.Body)
+	res, b, err := c.send(req)
 	if err != nil {
-		log.Default.With("Error", err).Debug("Error reading response.")
 		return nil, err
 	}
 
@@ -168,9 +266,9 @@ func (c *Client) doCodeWithABI(req *http.Request, v *CodeInfo) (*http.Response,
 
 // GetContractAddresses creates a new request to get contract addresses
 // from the gateway.
-func (c Client) GetContractAddresses() (*ContractAddresses, error) {
+func (c Client) GetContractAddresses(ctx context.Context) (*ContractAddresses, error) {
 	log.Default.With("Gateway URL", c.BaseURL).Info("Getting contract address from gateway.")
-	req, err := c.newRequest("GET", "/get_contract_addresses", nil, nil)
+	req, err := c.newRequest(ctx, "GET", "/get_contract_addresses", nil, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -178,15 +276,15 @@ func (c Client) GetContractAddresses() (*ContractAddresses, error) {
 	var res ContractAddresses
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
 // CallContract creates a new request to call a contract in the gateway.
-func (c Client) CallContract(invokeFunc InvokeFunction, blockHash, blockNumber string) (*map[string][]string, error) {
-	req, err := c.newRequest("POST", "/call_contract", formattedBlockIdentifier(blockHash, blockNumber), invokeFunc)
+func (c Client) CallContract(ctx context.Context, invokeFunc InvokeFunction, block BlockID) (*map[string][]string, error) {
+	req, err := c.newRequest(ctx, "POST", "/call_contract", formattedBlockIdentifier(block), invokeFunc)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -194,15 +292,15 @@ func (c Client) CallContract(invokeFunc InvokeFunction, blockHash, blockNumber s
 	var res map[string][]string
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
 // GetBlock creates a new request to get a block from the gateway.
-func (c Client) GetBlock(blockHash, blockNumber string) (*StarknetBlock, error) {
-	req, err := c.newRequest("GET", "/get_block", formattedBlockIdentifier(blockHash, blockNumber), nil)
+func (c Client) GetBlock(ctx context.Context, block BlockID) (*StarknetBlock, error) {
+	req, err := c.newRequest(ctx, "GET", "/get_block", formattedBlockIdentifier(block), nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -210,7 +308,7 @@ func (c Client) GetBlock(blockHash, blockNumber string) (*StarknetBlock, error)
 	var res StarknetBlock
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -218,8 +316,8 @@ func (c Client) GetBlock(blockHash, blockNumber string) (*StarknetBlock, error)
 
 // GetStateUpdate creates a new request to get the contract addresses
 // from the gateway.
-func (c Client) GetStateUpdate(blockHash, blockNumber string) (*StateUpdateResponse, error) {
-	req, err := c.newRequest("GET", "/get_state_update", formattedBlockIdentifier(blockHash, blockNumber), nil)
+func (c Client) GetStateUpdate(ctx context.Context, block BlockID) (*StateUpdateResponse, error) {
+	req, err := c.newRequest(ctx, "GET", "/get_state_update", formattedBlockIdentifier(block), nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -228,20 +326,16 @@ func (c Client) GetStateUpdate(blockHash, blockNumber string) (*StateUpdateRespo
 	var res StateUpdateResponse
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
-func (c Client) GetCode(contractAddress, blockHash, blockNumber string) (*CodeInfo, error) {
-	blockIdentifier := formattedBlockIdentifier(blockHash, blockNumber)
-	if blockIdentifier == nil {
-		// notest
-		blockIdentifier = map[string]string{}
-	}
+func (c Client) GetCode(ctx context.Context, contractAddress string, block BlockID) (*CodeInfo, error) {
+	blockIdentifier := formattedBlockIdentifier(block)
 	blockIdentifier["contractAddress"] = contractAddress
-	req, err := c.newRequest("GET", "/get_code", blockIdentifier, nil)
+	req, err := c.newRequest(ctx, "GET", "/get_code", blockIdentifier, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -249,48 +343,39 @@ func (c Client) GetCode(contractAddress, blockHash, blockNumber string) (*CodeIn
 	var res CodeInfo
 	_, err = c.doCodeWithABI(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
-// GetFullContract creates a new request to get the full state of a
+// GetFullContract creates a new request to get the definition of a
 // contract.
-func (c Client) GetFullContract(contractAddress, blockHash, blockNumber string) (map[string]interface{}, error) {
-	blockIdentifier := formattedBlockIdentifier(blockHash, blockNumber)
-	if blockIdentifier == nil {
-		// notest
-		blockIdentifier = map[string]string{}
-	}
+func (c Client) GetFullContract(ctx context.Context, contractAddress string, block BlockID) (*ContractDefinition, error) {
+	blockIdentifier := formattedBlockIdentifier(block)
 	blockIdentifier["contractAddress"] = contractAddress
 
-	req, err := c.newRequest("GET", "/get_full_contract", blockIdentifier, nil)
+	req, err := c.newRequest(ctx, "GET", "/get_full_contract", blockIdentifier, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
 	}
-	// var res []any
-	var res map[string]interface{}
+	var res ContractDefinition
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
-	return res, err
+	return &res, err
 }
 
 // GetStorageAt creates a new request to get contract storage.
-func (c Client) GetStorageAt(contractAddress, key, blockHash, blockNumber string) (*StorageInfo, error) {
-	blockIdentifier := formattedBlockIdentifier(blockHash, blockNumber)
-	if blockIdentifier == nil {
-		// notest
-		blockIdentifier = map[string]string{}
-	}
+func (c Client) GetStorageAt(ctx context.Context, contractAddress, key string, block BlockID) (*StorageInfo, error) {
+	blockIdentifier := formattedBlockIdentifier(block)
 	blockIdentifier["contractAddress"] = contractAddress
 	blockIdentifier["key"] = key
 
-	req, err := c.newRequest("GET", "/get_storage_at",
+	req, err := c.newRequest(ctx, "GET", "/get_storage_at",
 		blockIdentifier, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
@@ -300,7 +385,7 @@ func (c Client) GetStorageAt(contractAddress, key, blockHash, blockNumber string
 	_, err = c.do(req, &res)
 
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -308,8 +393,8 @@ func (c Client) GetStorageAt(contractAddress, key, blockHash, blockNumber string
 
 // GetTransactionStatus creates a new request to get the transaction
 // status.
-func (c Client) GetTransactionStatus(txHash, txID string) (*TransactionStatus, error) {
-	req, err := c.newRequest("GET", "/get_transaction_status", TxnIdentifier(txHash, txID), nil)
+func (c Client) GetTransactionStatus(ctx context.Context, txHash, txID string) (*TransactionStatus, error) {
+	req, err := c.newRequest(ctx, "GET", "/get_transaction_status", TxnIdentifier(txHash, txID), nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -317,15 +402,15 @@ func (c Client) GetTransactionStatus(txHash, txID string) (*TransactionStatus, e
 	var res TransactionStatus
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
 // GetTransaction creates a new request to get a TransactionInfo.
-func (c Client) GetTransaction(txHash, txID string) (*TransactionInfo, error) {
-	req, err := c.newRequest("GET", "/get_transaction", TxnIdentifier(txHash, txID), nil)
+func (c Client) GetTransaction(ctx context.Context, txHash, txID string) (*TransactionInfo, error) {
+	req, err := c.newRequest(ctx, "GET", "/get_transaction", TxnIdentifier(txHash, txID), nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -333,7 +418,7 @@ func (c Client) GetTransaction(txHash, txID string) (*TransactionInfo, error) {
 	var res TransactionInfo
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -341,8 +426,8 @@ func (c Client) GetTransaction(txHash, txID string) (*TransactionInfo, error) {
 
 // GetTransactionReceipt creates a new request to get a
 // TransactionReceipt.
-func (c Client) GetTransactionReceipt(txHash, txID string) (*TransactionReceipt, error) {
-	req, err := c.newRequest("GET", "/get_transaction_receipt", TxnIdentifier(txHash, txID), nil)
+func (c Client) GetTransactionReceipt(ctx context.Context, txHash, txID string) (*TransactionReceipt, error) {
+	req, err := c.newRequest(ctx, "GET", "/get_transaction_receipt", TxnIdentifier(txHash, txID), nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
 		return nil, err
@@ -350,15 +435,15 @@ func (c Client) GetTransactionReceipt(txHash, txID string) (*TransactionReceipt,
 	var res TransactionReceipt
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
 // GetBlockHashById creates a new request to get block hash by on ID.
-func (c Client) GetBlockHashById(blockID string) (*string, error) {
-	req, err := c.newRequest(
+func (c Client) GetBlockHashById(ctx context.Context, blockID string) (*string, error) {
+	req, err := c.newRequest(ctx,
 		"GET", "/get_block_hash_by_id", map[string]string{"blockId": blockID}, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
@@ -367,15 +452,15 @@ func (c Client) GetBlockHashById(blockID string) (*string, error) {
 	var res string
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
 }
 
 // GetBlockIDByHash creates a new request to get the block ID by hash.
-func (c Client) GetBlockIDByHash(blockHash string) (*string, error) {
-	req, err := c.newRequest(
+func (c Client) GetBlockIDByHash(ctx context.Context, blockHash string) (*string, error) {
+	req, err := c.newRequest(ctx,
 		"GET", "/get_block_id_by_hash", map[string]string{"blockHash": blockHash}, nil)
 	if err != nil {
 		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Unable to create a request for get_contract_addresses.")
@@ -384,7 +469,7 @@ func (c Client) GetBlockIDByHash(blockHash string) (*string, error) {
 	var res string
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -392,8 +477,8 @@ func (c Client) GetBlockIDByHash(blockHash string) (*string, error) {
 
 // GetTransactionHashByID creates a new request to get a transaction
 // hash by ID.
-func (c Client) GetTransactionHashByID(txID string) (*string, error) {
-	req, err := c.newRequest(
+func (c Client) GetTransactionHashByID(ctx context.Context, txID string) (*string, error) {
+	req, err := c.newRequest(ctx,
 		"GET", "/get_transaction_hash_by_id",
 		map[string]string{"transactionId": txID}, nil)
 	if err != nil {
@@ -403,7 +488,7 @@ func (c Client) GetTransactionHashByID(txID string) (*string, error) {
 	var res string
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -411,8 +496,8 @@ func (c Client) GetTransactionHashByID(txID string) (*string, error) {
 
 // GetTransactionIDByHash creates a new request to get a transaction ID
 // by hash.
-func (c Client) GetTransactionIDByHash(txHash string) (*string, error) {
-	req, err := c.newRequest(
+func (c Client) GetTransactionIDByHash(ctx context.Context, txHash string) (*string, error) {
+	req, err := c.newRequest(ctx,
 		"GET", "/get_transaction_id_by_hash",
 		map[string]string{"transactionHash": txHash}, nil)
 	if err != nil {
@@ -422,8 +507,7 @@ func (c Client) GetTransactionIDByHash(txHash string) (*string, error) {
 	var res string
 	_, err = c.do(req, &res)
 	if err != nil {
-		log.Default.With("Error", err, "Gateway URL", c.BaseURL).
-			Error("Error connecting to the gateway.")
+		c.logRequestError(err)
 		return nil, err
 	}
 	return &res, err
@@ -0,0 +1,421 @@
+package feedertest
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/types"
+)
+
+// emptyCode is the code returned for the contracts without a code.
+var emptyCode = json.RawMessage(`{"bytecode": [], "abi": []}`)
+
+// Chain is a Source that serves a chain built in memory. The blocks are
+// appended one after the other with their state updates, and the chain can be
+// truncated to simulate a reorganization. The storage of the contracts is
+// computed from the state updates, and the code of the contracts is set
+// apart. It's safe to modify the chain while it's being served.
+type Chain struct {
+	mu           sync.RWMutex
+	blocks       []*feeder.StarknetBlock
+	stateUpdates []*feeder.StateUpdateResponse
+	pending      *feeder.StarknetBlock
+	// codes and contracts are the code and full contract definition of the
+	// contracts, by normalized address.
+	codes     map[string]json.RawMessage
+	contracts map[string]json.RawMessage
+}
+
+// NewChain returns an empty Chain.
+func NewChain() *Chain {
+	return &Chain{
+		codes:     make(map[string]json.RawMessage),
+		contracts: make(map[string]json.RawMessage),
+	}
+}
+
+// Append adds a block and its state update on top of the chain. The number of
+// the block is set to its height in the chain.
+func (c *Chain) Append(b *feeder.StarknetBlock, update *feeder.StateUpdateResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b.BlockNumber = types.BlockNumber(len(c.blocks))
+	c.blocks = append(c.blocks, b)
+	c.stateUpdates = append(c.stateUpdates, update)
+}
+
+// Truncate removes the blocks from the given block number on, like a chain
+// reorganization does before the blocks of the new branch are appended.
+func (c *Chain) Truncate(blockNumber uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if blockNumber < uint64(len(c.blocks)) {
+		c.blocks = c.blocks[:blockNumber]
+		c.stateUpdates = c.stateUpdates[:blockNumber]
+	}
+}
+
+// SetPending sets the pending block, or removes it if b is nil.
+func (c *Chain) SetPending(b *feeder.StarknetBlock) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pending = b
+}
+
+// SetCode sets the response of get_code for the contract with the given
+// address. The contracts deployed without a code have an empty one.
+func (c *Chain) SetCode(address string, code json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.codes[normalize(address)] = code
+}
+
+// SetFullContract sets the response of get_full_contract for the contract
+// with the given address.
+func (c *Chain) SetFullContract(address string, contract json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.contracts[normalize(address)] = contract
+}
+
+// Get implements the Source interface.
+func (c *Chain) Get(endpoint string, query url.Values) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	switch endpoint {
+	case "get_block":
+		return c.getBlock(query)
+	case "get_state_update":
+		return c.getStateUpdate(query)
+	case "get_code":
+		return c.getCode(query)
+	case "get_full_contract":
+		return c.getFullContract(query)
+	case "get_storage_at":
+		return c.getStorageAt(query)
+	case "get_transaction":
+		return c.getTransaction(query)
+	case "get_transaction_status":
+		return c.getTransactionStatus(query)
+	case "get_transaction_receipt":
+		return c.getTransactionReceipt(query)
+	case "get_block_hash_by_id":
+		return c.getBlockHashByID(query)
+	case "get_block_id_by_hash":
+		return c.getBlockIDByHash(query)
+	case "get_transaction_hash_by_id":
+		return c.getTransactionHashByID(query)
+	case "get_transaction_id_by_hash":
+		return c.getTransactionIDByHash(query)
+	}
+	return nil, notFound(endpoint)
+}
+
+// isPending returns true if the query params identify the pending block.
+func isPending(query url.Values) bool {
+	return query.Get("blockNumber") == "pending"
+}
+
+// block returns the number of the block identified by the query params, as
+// the "blockHash" or "blockNumber" params do. Without any of them, the latest
+// block is returned. Returns a *feeder.Error if the block does not exist.
+func (c *Chain) block(endpoint string, query url.Values) (uint64, error) {
+	if hash := query.Get("blockHash"); hash != "" {
+		for i, b := range c.blocks {
+			if normalize(b.BlockHash) == normalize(hash) {
+				return uint64(i), nil
+			}
+		}
+		return 0, notFound(endpoint)
+	}
+	number := query.Get("blockNumber")
+	if number == "" || number == "latest" {
+		if len(c.blocks) == 0 {
+			return 0, notFound(endpoint)
+		}
+		return uint64(len(c.blocks) - 1), nil
+	}
+	n, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, malformed("Invalid block number " + number)
+	}
+	if n >= uint64(len(c.blocks)) {
+		return 0, notFound(endpoint)
+	}
+	return n, nil
+}
+
+func (c *Chain) getBlock(query url.Values) ([]byte, error) {
+	if isPending(query) {
+		if c.pending == nil {
+			return nil, notFound("get_block")
+		}
+		return marshalPending(c.pending)
+	}
+	n, err := c.block("get_block", query)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.blocks[n])
+}
+
+// marshalPending encodes the given pending block, which has no hash, number
+// or state root yet.
+func marshalPending(b *feeder.StarknetBlock) ([]byte, error) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "block_hash")
+	delete(fields, "block_number")
+	delete(fields, "state_root")
+	return json.Marshal(fields)
+}
+
+func (c *Chain) getStateUpdate(query url.Values) ([]byte, error) {
+	if isPending(query) {
+		return nil, notFound("get_state_update")
+	}
+	n, err := c.block("get_state_update", query)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c.stateUpdates[n])
+}
+
+// deployed returns true if the contract with the given normalized address is
+// deployed in the given block or any block before it.
+func (c *Chain) deployed(address string, blockNumber uint64) bool {
+	for _, update := range c.stateUpdates[:blockNumber+1] {
+		for _, contract := range update.StateDiff.DeployedContracts {
+			if normalize(contract.Address) == address {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Chain) getCode(query url.Values) ([]byte, error) {
+	n, err := c.block("get_code", query)
+	if err != nil {
+		return nil, err
+	}
+	address := normalize(query.Get("contractAddress"))
+	if !c.deployed(address, n) {
+		return emptyCode, nil
+	}
+	if code, ok := c.codes[address]; ok {
+		return code, nil
+	}
+	return emptyCode, nil
+}
+
+func (c *Chain) getFullContract(query url.Values) ([]byte, error) {
+	n, err := c.block("get_full_contract", query)
+	if err != nil {
+		return nil, err
+	}
+	address := normalize(query.Get("contractAddress"))
+	contract, ok := c.contracts[address]
+	if !ok || !c.deployed(address, n) {
+		return nil, notFound("get_full_contract")
+	}
+	return contract, nil
+}
+
+func (c *Chain) getStorageAt(query url.Values) ([]byte, error) {
+	n, err := c.block("get_storage_at", query)
+	if err != nil {
+		return nil, err
+	}
+	address, key := normalize(query.Get("contractAddress")), normalize(query.Get("key"))
+	value := "0x0"
+	for _, update := range c.stateUpdates[:n+1] {
+		for diffAddress, diffs := range update.StateDiff.StorageDiffs {
+			if normalize(diffAddress) != address {
+				continue
+			}
+			for _, diff := range diffs {
+				if normalize(diff.Key) == key {
+					value = diff.Value
+				}
+			}
+		}
+	}
+	return json.Marshal(value)
+}
+
+// txLocation is the location of a transaction in the chain.
+type txLocation struct {
+	// block is the block of the transaction, which may be the pending
+	// block.
+	block *feeder.StarknetBlock
+	// index is the index of the transaction in the block.
+	index int
+	// id is the number of transactions before it in the chain. The
+	// transactions of the pending block have no id.
+	id int
+}
+
+// transaction returns the location of the transaction identified by the
+// query params, as the "transactionHash" or "transactionId" params do.
+// Returns false if the transaction does not exist.
+func (c *Chain) transaction(query url.Values) (txLocation, bool) {
+	hash, rawID := query.Get("transactionHash"), query.Get("transactionId")
+	id, err := strconv.Atoi(rawID)
+	if hash == "" && err != nil {
+		return txLocation{}, false
+	}
+	match := func(loc txLocation) bool {
+		if hash != "" {
+			return normalize(loc.block.Transactions[loc.index].TransactionHash) == normalize(hash)
+		}
+		return loc.id == id
+	}
+	next := 0
+	for _, b := range c.blocks {
+		for i := range b.Transactions {
+			loc := txLocation{block: b, index: i, id: next}
+			if match(loc) {
+				return loc, true
+			}
+			next++
+		}
+	}
+	if c.pending != nil && hash != "" {
+		for i := range c.pending.Transactions {
+			loc := txLocation{block: c.pending, index: i, id: -1}
+			if match(loc) {
+				return loc, true
+			}
+		}
+	}
+	return txLocation{}, false
+}
+
+// inBlockInfo returns the fields that describe where the transaction is in
+// the chain, as the gateway adds them to the transaction responses.
+func (c *Chain) inBlockInfo(loc txLocation) map[string]interface{} {
+	if loc.block == c.pending {
+		return map[string]interface{}{"status": "PENDING"}
+	}
+	return map[string]interface{}{
+		"status":            loc.block.Status,
+		"block_hash":        loc.block.BlockHash,
+		"block_number":      int64(loc.block.BlockNumber),
+		"transaction_index": loc.index,
+	}
+}
+
+func (c *Chain) getTransaction(query url.Values) ([]byte, error) {
+	loc, ok := c.transaction(query)
+	if !ok {
+		return json.Marshal(map[string]string{"status": "NOT_RECEIVED"})
+	}
+	res := c.inBlockInfo(loc)
+	res["transaction"] = loc.block.Transactions[loc.index]
+	return json.Marshal(res)
+}
+
+func (c *Chain) getTransactionStatus(query url.Values) ([]byte, error) {
+	loc, ok := c.transaction(query)
+	if !ok {
+		return json.Marshal(feeder.TransactionStatus{Status: "NOT_RECEIVED"})
+	}
+	status := feeder.TransactionStatus{Status: "PENDING"}
+	if loc.block != c.pending {
+		status = feeder.TransactionStatus{Status: loc.block.Status, BlockHash: loc.block.BlockHash}
+	}
+	return json.Marshal(status)
+}
+
+func (c *Chain) getTransactionReceipt(query url.Values) ([]byte, error) {
+	loc, ok := c.transaction(query)
+	if !ok {
+		return json.Marshal(map[string]string{
+			"status":           "NOT_RECEIVED",
+			"transaction_hash": query.Get("transactionHash"),
+		})
+	}
+	res := c.inBlockInfo(loc)
+	hash := loc.block.Transactions[loc.index].TransactionHash
+	for _, receipt := range loc.block.TransactionReceipts {
+		if normalize(receipt.TransactionHash) != normalize(hash) {
+			continue
+		}
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return nil, err
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			res[k] = v
+		}
+	}
+	res["transaction_hash"] = hash
+	return json.Marshal(res)
+}
+
+// The responses of the ID/hash mapping endpoints are strings, as the
+// feeder.Client expects them.
+
+func (c *Chain) getBlockHashByID(query url.Values) ([]byte, error) {
+	n, err := strconv.ParseUint(query.Get("blockId"), 10, 64)
+	if err != nil {
+		return nil, malformed("Invalid block id " + query.Get("blockId"))
+	}
+	if n >= uint64(len(c.blocks)) {
+		return nil, notFound("get_block_hash_by_id")
+	}
+	return json.Marshal(c.blocks[n].BlockHash)
+}
+
+func (c *Chain) getBlockIDByHash(query url.Values) ([]byte, error) {
+	if query.Get("blockHash") == "" {
+		return nil, malformed("Missing block hash")
+	}
+	n, err := c.block("get_block_id_by_hash", query)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(strconv.FormatUint(n, 10))
+}
+
+func (c *Chain) getTransactionHashByID(query url.Values) ([]byte, error) {
+	if _, err := strconv.Atoi(query.Get("transactionId")); err != nil {
+		return nil, malformed("Invalid transaction id " + query.Get("transactionId"))
+	}
+	loc, ok := c.transaction(url.Values{"transactionId": query["transactionId"]})
+	if !ok {
+		return nil, notFound("get_transaction_hash_by_id")
+	}
+	return json.Marshal(loc.block.Transactions[loc.index].TransactionHash)
+}
+
+func (c *Chain) getTransactionIDByHash(query url.Values) ([]byte, error) {
+	loc, ok := c.transaction(url.Values{"transactionHash": query["transactionHash"]})
+	if !ok || loc.id < 0 {
+		return nil, notFound("get_transaction_id_by_hash")
+	}
+	return json.Marshal(strconv.Itoa(loc.id))
+}
+
+// normalize returns the canonical representation of the given hex-encoded
+// field element, so the same hashes and addresses match even if they are
+// written with different padding.
+func normalize(s string) string {
+	return common.HexToFelt(s).Hex()
+}
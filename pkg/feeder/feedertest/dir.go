@@ -0,0 +1,64 @@
+package feedertest
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// Dir is a Source that serves the responses recorded in a directory, like the
+// fixture archives written by a Recorder. The response to each request is read
+// from the file at FixturePath, relative to the directory. The requests
+// without a recorded response fail as if the requested object did not exist.
+type Dir string
+
+// Get implements the Source interface.
+func (d Dir) Get(endpoint string, query url.Values) ([]byte, error) {
+	name := FixturePath(endpoint, query)
+	if !fs.ValidPath(name) {
+		return nil, notFound(endpoint)
+	}
+	body, err := os.ReadFile(d.path(name))
+	if !errors.Is(err, fs.ErrNotExist) {
+		return body, err
+	}
+
+	// The request may have been recorded as failed.
+	data, err := os.ReadFile(d.path(errorFixturePath(endpoint, query)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, notFound(endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var fixture errorFixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, err
+	}
+	return nil, &feeder.Error{StatusCode: fixture.StatusCode, Code: fixture.Code, Message: fixture.Message}
+}
+
+// path returns the path of the file with the given slash-separated path,
+// relative to the directory.
+func (d Dir) path(name string) string {
+	return filepath.Join(string(d), filepath.FromSlash(name))
+}
+
+// FixturePath returns the path, relative to a Dir, of the file with the
+// response to a request to the given endpoint with the given query params.
+// The file is named after the encoded query params, inside a directory named
+// after the endpoint; for example, "get_block/blockNumber=1.json". The path
+// is slash-separated.
+func FixturePath(endpoint string, query url.Values) string {
+	name := query.Encode()
+	if name == "" {
+		name = "_"
+	}
+	return path.Join(endpoint, name+".json")
+}
@@ -0,0 +1,213 @@
+package feedertest_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feedertest"
+)
+
+// newChain returns a chain of two blocks: the genesis block deploys a contract
+// and sets its storage, and the block 1 invokes the contract and updates the
+// storage.
+func newChain() *feedertest.Chain {
+	chain := feedertest.NewChain()
+	var genesis feeder.StateUpdateResponse
+	genesis.BlockHash = "0x1"
+	genesis.StateDiff.DeployedContracts = append(genesis.StateDiff.DeployedContracts, struct {
+		Address      string `json:"address"`
+		ContractHash string `json:"contract_hash"`
+	}{Address: "0xc", ContractHash: "0xcc"})
+	genesis.StateDiff.StorageDiffs = map[string][]feeder.KV{"0xc": {{Key: "0x5", Value: "0x22b"}}}
+	chain.Append(&feeder.StarknetBlock{
+		BlockHash:       "0x1",
+		ParentBlockHash: "0x0",
+		Status:          "ACCEPTED_ON_L1",
+		Transactions:    []feeder.TxnSpecificInfo{{TransactionHash: "0x10", ContractAddress: "0xc", Type: "DEPLOY"}},
+	}, &genesis)
+
+	var update feeder.StateUpdateResponse
+	update.BlockHash = "0x2"
+	update.StateDiff.StorageDiffs = map[string][]feeder.KV{"0x00c": {{Key: "0x05", Value: "0x22c"}}}
+	chain.Append(&feeder.StarknetBlock{
+		BlockHash:           "0x2",
+		ParentBlockHash:     "0x1",
+		Status:              "ACCEPTED_ON_L2",
+		Transactions:        []feeder.TxnSpecificInfo{{TransactionHash: "0x20", ContractAddress: "0xc", Type: "INVOKE_FUNCTION"}},
+		TransactionReceipts: []feeder.TransactionExecution{{TransactionHash: "0x20", ActualFee: "0x7"}},
+	}, &update)
+	chain.SetCode("0xc", []byte(`{"bytecode": ["0x1", "0x2"], "abi": []}`))
+	chain.SetFullContract("0xc", []byte(`{"program": {}}`))
+	return chain
+}
+
+func TestChain(t *testing.T) {
+	chain := newChain()
+	server := feedertest.NewServer(chain)
+	defer server.Close()
+	client := feedertest.NewClient(server)
+	ctx := context.Background()
+
+	t.Run("blocks", func(t *testing.T) {
+		for id, want := range map[feeder.BlockID]string{
+			feeder.LatestBlock:      "0x2",
+			feeder.BlockNumber(0):   "0x1",
+			feeder.BlockHash("0x2"): "0x2",
+		} {
+			b, err := client.GetBlock(ctx, id)
+			if err != nil {
+				t.Fatalf("unexpected error getting block %s: %s", id, err)
+			}
+			if b.BlockHash != want {
+				t.Errorf("unexpected hash %s for block %s, want %s", b.BlockHash, id, want)
+			}
+		}
+		if _, err := client.GetBlock(ctx, feeder.BlockNumber(2)); !errors.Is(err, feeder.ErrNotFound) {
+			t.Errorf("unexpected error %v for a missing block", err)
+		}
+		update, err := client.GetStateUpdate(ctx, feeder.BlockNumber(1))
+		if err != nil {
+			t.Fatalf("unexpected error getting the state update: %s", err)
+		}
+		if update.BlockHash != "0x2" {
+			t.Errorf("unexpected state update of block %s", update.BlockHash)
+		}
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		if _, err := client.GetBlock(ctx, feeder.PendingBlock); !errors.Is(err, feeder.ErrNotFound) {
+			t.Errorf("unexpected error %v for a missing pending block", err)
+		}
+		chain.SetPending(&feeder.StarknetBlock{
+			ParentBlockHash: "0x2",
+			Status:          "PENDING",
+			Transactions:    []feeder.TxnSpecificInfo{{TransactionHash: "0x30"}},
+		})
+		defer chain.SetPending(nil)
+		b, err := client.GetBlock(ctx, feeder.PendingBlock)
+		if err != nil {
+			t.Fatalf("unexpected error getting the pending block: %s", err)
+		}
+		if b.ParentBlockHash != "0x2" || len(b.Transactions) != 1 {
+			t.Errorf("unexpected pending block %+v", b)
+		}
+		status, err := client.GetTransactionStatus(ctx, "0x30", "")
+		if err != nil {
+			t.Fatalf("unexpected error getting the transaction status: %s", err)
+		}
+		if status.Status != "PENDING" {
+			t.Errorf("unexpected status %s of a pending transaction", status.Status)
+		}
+	})
+
+	t.Run("contracts", func(t *testing.T) {
+		for block, want := range map[feeder.BlockID]string{
+			feeder.BlockNumber(0): "0x22b",
+			feeder.LatestBlock:    "0x22c",
+		} {
+			value, err := client.GetStorageAt(ctx, "0xc", "0x5", block)
+			if err != nil {
+				t.Fatalf("unexpected error getting the storage: %s", err)
+			}
+			if string(*value) != want {
+				t.Errorf("unexpected storage value %s at block %s, want %s", *value, block, want)
+			}
+		}
+		code, err := client.GetCode(ctx, "0x0c", feeder.LatestBlock)
+		if err != nil {
+			t.Fatalf("unexpected error getting the code: %s", err)
+		}
+		if len(code.Bytecode) != 2 {
+			t.Errorf("unexpected bytecode %v", code.Bytecode)
+		}
+		if _, err := client.GetFullContract(ctx, "0xc", feeder.LatestBlock); err != nil {
+			t.Errorf("unexpected error getting the full contract: %s", err)
+		}
+		if _, err := client.GetFullContract(ctx, "0xd", feeder.LatestBlock); err == nil {
+			t.Errorf("expected an error getting the full contract of an unknown contract")
+		}
+	})
+
+	t.Run("transactions", func(t *testing.T) {
+		tx, err := client.GetTransaction(ctx, "", "1")
+		if err != nil {
+			t.Fatalf("unexpected error getting the transaction: %s", err)
+		}
+		if tx.Transaction.TransactionHash != "0x20" {
+			t.Errorf("unexpected transaction %s with id 1", tx.Transaction.TransactionHash)
+		}
+		receipt, err := client.GetTransactionReceipt(ctx, "0x20", "")
+		if err != nil {
+			t.Fatalf("unexpected error getting the receipt: %s", err)
+		}
+		if receipt.ActualFee != "0x7" || receipt.Status != "ACCEPTED_ON_L2" || receipt.BlockHash != "0x2" {
+			t.Errorf("unexpected receipt %+v", receipt)
+		}
+		status, err := client.GetTransactionStatus(ctx, "0x99", "")
+		if err != nil {
+			t.Fatalf("unexpected error getting the transaction status: %s", err)
+		}
+		if status.Status != "NOT_RECEIVED" {
+			t.Errorf("unexpected status %s of an unknown transaction", status.Status)
+		}
+	})
+
+	t.Run("ids", func(t *testing.T) {
+		if hash, err := client.GetBlockHashById(ctx, "1"); err != nil || *hash != "0x2" {
+			t.Errorf("unexpected block hash %v with error %v", hash, err)
+		}
+		if id, err := client.GetBlockIDByHash(ctx, "0x1"); err != nil || *id != "0" {
+			t.Errorf("unexpected block id %v with error %v", id, err)
+		}
+		if hash, err := client.GetTransactionHashByID(ctx, "0"); err != nil || *hash != "0x10" {
+			t.Errorf("unexpected transaction hash %v with error %v", hash, err)
+		}
+		if id, err := client.GetTransactionIDByHash(ctx, "0x20"); err != nil || *id != "1" {
+			t.Errorf("unexpected transaction id %v with error %v", id, err)
+		}
+		if _, err := client.GetBlockHashById(ctx, "5"); !errors.Is(err, feeder.ErrNotFound) {
+			t.Errorf("unexpected error %v for a missing block", err)
+		}
+	})
+
+	t.Run("reorg", func(t *testing.T) {
+		chain.Truncate(1)
+		if _, err := client.GetBlock(ctx, feeder.BlockHash("0x2")); !errors.Is(err, feeder.ErrNotFound) {
+			t.Errorf("unexpected error %v for a truncated block", err)
+		}
+		if b, err := client.GetBlock(ctx, feeder.LatestBlock); err != nil || b.BlockHash != "0x1" {
+			t.Errorf("unexpected latest block %v with error %v", b, err)
+		}
+	})
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	fixture := filepath.Join(dir, filepath.FromSlash(feedertest.FixturePath("get_block", url.Values{"blockNumber": {"7"}})))
+	if err := os.MkdirAll(filepath.Dir(fixture), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fixture, []byte(`{"block_hash": "0x7", "block_number": 7}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := feedertest.NewServer(feedertest.Dir(dir))
+	defer server.Close()
+	client := feedertest.NewClient(server)
+
+	b, err := client.GetBlock(context.Background(), feeder.BlockNumber(7))
+	if err != nil {
+		t.Fatalf("unexpected error getting a recorded block: %s", err)
+	}
+	if b.BlockHash != "0x7" {
+		t.Errorf("unexpected block hash %s, want 0x7", b.BlockHash)
+	}
+	if _, err := client.GetBlock(context.Background(), feeder.BlockNumber(8)); !errors.Is(err, feeder.ErrNotFound) {
+		t.Errorf("unexpected error %v for a block that was not recorded", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package feedertest provides a stand-in for the StarkNet feeder gateway, to
+// develop and test without network access.
+//
+// The Gateway handler answers the feeder gateway endpoints using a Source,
+// which can be a directory of recorded responses (see Dir) or a programmable
+// in-memory chain (see Chain). NewServer serves a Source with an
+// httptest.Server, and NewClient returns a feeder.Client connected to it:
+//
+//	chain := feedertest.NewChain()
+//	chain.Append(block, stateUpdate)
+//	server := feedertest.NewServer(chain)
+//	defer server.Close()
+//	client := feedertest.NewClient(server)
+package feedertest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// StarkNet error codes returned by the gateway.
+const (
+	blockNotFound         = "StarknetErrorCode.BLOCK_NOT_FOUND"
+	transactionNotFound   = "StarknetErrorCode.TRANSACTION_NOT_FOUND"
+	uninitializedContract = "StarknetErrorCode.UNINITIALIZED_CONTRACT"
+	malformedRequest      = "StarkErrorCode.MALFORMED_REQUEST"
+)
+
+// Source provides the responses of a Gateway.
+type Source interface {
+	// Get returns the JSON body of the response to a request to the given
+	// endpoint, like "get_block", with the given query params. If the
+	// request fails, like when the requested object does not exist, a
+	// *feeder.Error is returned.
+	Get(endpoint string, query url.Values) ([]byte, error)
+}
+
+// Gateway is an http.Handler that answers the requests to the feeder gateway
+// endpoints with the responses of a Source. The endpoint is the last element
+// of the request path, so the Gateway can be mounted under any prefix.
+type Gateway struct {
+	source Source
+}
+
+// NewGateway returns a Gateway that serves the given source.
+func NewGateway(source Source) *Gateway {
+	return &Gateway{source: source}
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := g.source.Get(path.Base(r.URL.Path), r.URL.Query())
+	if err != nil {
+		var gatewayErr *feeder.Error
+		if !errors.As(err, &gatewayErr) {
+			gatewayErr = &feeder.Error{StatusCode: http.StatusInternalServerError, Message: err.Error()}
+		}
+		writeError(w, gatewayErr)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+// writeError writes the given error with the format of the StarkNet errors.
+func writeError(w http.ResponseWriter, err *feeder.Error) {
+	body, _ := json.Marshal(struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}{err.Code, err.Message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.StatusCode)
+	w.Write(body)
+}
+
+// NewServer starts and returns an httptest.Server that serves the given
+// source. The caller must close the server when finished.
+func NewServer(source Source) *httptest.Server {
+	return httptest.NewServer(NewGateway(source))
+}
+
+// NewClient returns a feeder.Client that sends its requests to the given
+// server. The client is not rate limited, and it retries the failed requests
+// without waiting.
+func NewClient(server *httptest.Server) *feeder.Client {
+	return newClient(server.URL, server.Client())
+}
+
+// newClient returns a feeder.Client for the given base URL that sends its
+// requests with the given client, without rate limits and retrying the failed
+// requests without waiting.
+func newClient(baseURL string, httpClient feeder.HttpClient) *feeder.Client {
+	client := feeder.NewClient(baseURL, "/feeder_gateway", &httpClient)
+	client.RateLimiter = nil
+	client.Retry = feeder.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	return client
+}
+
+// notFound returns the error returned by the gateway when the object
+// requested to the given endpoint does not exist.
+func notFound(endpoint string) error {
+	switch endpoint {
+	case "get_block", "get_state_update", "get_block_hash_by_id", "get_block_id_by_hash":
+		return &feeder.Error{StatusCode: http.StatusBadRequest, Code: blockNotFound, Message: "Block not found"}
+	case "get_code", "get_full_contract", "get_storage_at":
+		return &feeder.Error{StatusCode: http.StatusBadRequest, Code: uninitializedContract, Message: "Contract not found"}
+	case "get_transaction", "get_transaction_status", "get_transaction_receipt",
+		"get_transaction_hash_by_id", "get_transaction_id_by_hash":
+		return &feeder.Error{StatusCode: http.StatusBadRequest, Code: transactionNotFound, Message: "Transaction not found"}
+	}
+	return &feeder.Error{StatusCode: http.StatusNotFound, Message: "Unknown endpoint " + endpoint}
+}
+
+// malformed returns the error returned by the gateway when the query params
+// of a request are not valid.
+func malformed(message string) error {
+	return &feeder.Error{StatusCode: http.StatusBadRequest, Code: malformedRequest, Message: message}
+}
@@ -0,0 +1,168 @@
+package feedertest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// bodyParam is the query param added to the fixtures of the requests with a
+// body, like call_contract, so requests with different bodies are recorded
+// apart.
+const bodyParam = "requestBody"
+
+// errorFixture is the content of the fixture of a request that failed.
+type errorFixture struct {
+	StatusCode int    `json:"status_code"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+}
+
+// Recorder is a feeder.HttpClient that sends the requests with another
+// feeder.HttpClient and records the responses to a fixture archive: a
+// directory that can be replayed with Dir, NewReplayer or NewReplayClient.
+//
+// The successful responses are recorded as they are, while for the responses
+// with an unsuccessful status code only the StarkNet error is recorded. The
+// responses that fail with a transient error, which would succeed if the
+// request is retried, are not recorded.
+type Recorder struct {
+	client feeder.HttpClient
+	dir    Dir
+}
+
+// NewRecorder returns a Recorder that sends the requests with the given client
+// and records the responses to the given directory.
+func NewRecorder(client feeder.HttpClient, dir string) *Recorder {
+	return &Recorder{client: client, dir: Dir(dir)}
+}
+
+// Do implements the feeder.HttpClient interface.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	query, err := fixtureQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+
+	endpoint := path.Base(req.URL.Path)
+	switch {
+	case res.StatusCode == 0 || (res.StatusCode >= 200 && res.StatusCode <= 299):
+		err = r.write(FixturePath(endpoint, query), body)
+	case res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError:
+		// The request will be retried.
+	default:
+		fixture := errorFixture{StatusCode: res.StatusCode}
+		_ = json.Unmarshal(body, &fixture)
+		var data []byte
+		if data, err = json.Marshal(fixture); err == nil {
+			err = r.write(errorFixturePath(endpoint, query), data)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// write writes the data to the file with the given slash-separated path,
+// relative to the directory of the recorder. The file is written at once, so
+// it's never read half written.
+func (r *Recorder) write(name string, data []byte) error {
+	name = r.dir.path(name)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), ".recording-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Replayer is a feeder.HttpClient that answers the requests with the responses
+// of a Source, like a fixture archive recorded by a Recorder, without network
+// access. The same request always gets the same response.
+type Replayer struct {
+	gateway *Gateway
+}
+
+// NewReplayer returns a Replayer that serves the given source.
+func NewReplayer(source Source) *Replayer {
+	return &Replayer{gateway: NewGateway(source)}
+}
+
+// Do implements the feeder.HttpClient interface.
+func (r *Replayer) Do(req *http.Request) (*http.Response, error) {
+	query, err := fixtureQuery(req)
+	if err != nil {
+		return nil, err
+	}
+	replayed := req.Clone(req.Context())
+	replayed.URL.RawQuery = query.Encode()
+	w := httptest.NewRecorder()
+	r.gateway.ServeHTTP(w, replayed)
+	return w.Result(), nil
+}
+
+// NewReplayClient returns a feeder.Client that gets its responses from the
+// given source using a Replayer. Like the client returned by NewClient, it's
+// not rate limited.
+func NewReplayClient(source Source) *feeder.Client {
+	return newClient("https://replay", NewReplayer(source))
+}
+
+// fixtureQuery returns the query params that identify the fixture of the
+// given request. For the requests with a body, the hash of the body is added
+// to the query params. The body of the request can still be read afterwards.
+func fixtureQuery(req *http.Request) (url.Values, error) {
+	query := req.URL.Query()
+	if req.Body == nil || req.Body == http.NoBody {
+		return query, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if len(body) > 0 {
+		hash := sha256.Sum256(body)
+		query.Set(bodyParam, hex.EncodeToString(hash[:]))
+	}
+	return query, nil
+}
+
+// errorFixturePath returns the path, relative to a Dir, of the file with the
+// error of a failed request to the given endpoint with the given query params.
+func errorFixturePath(endpoint string, query url.Values) string {
+	name := FixturePath(endpoint, query)
+	return name[:len(name)-len(".json")] + ".error.json"
+}
@@ -0,0 +1,103 @@
+package feedertest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feedertest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := feedertest.NewServer(newChain())
+	defer server.Close()
+	dir := t.TempDir()
+
+	// Record the responses of a live gateway.
+	var recorder feeder.HttpClient = feedertest.NewRecorder(server.Client(), dir)
+	recording := feeder.NewClient(server.URL, "/feeder_gateway", &recorder)
+	recording.RateLimiter = nil
+	ctx := context.Background()
+	want, err := recording.GetBlock(ctx, feeder.BlockNumber(1))
+	if err != nil {
+		t.Fatalf("unexpected error recording a block: %s", err)
+	}
+	if _, err := recording.GetBlock(ctx, feeder.BlockNumber(2)); !errors.Is(err, feeder.ErrNotFound) {
+		t.Fatalf("unexpected error %v recording a missing block", err)
+	}
+	if _, err := recording.GetStorageAt(ctx, "0xc", "0x5", feeder.LatestBlock); err != nil {
+		t.Fatalf("unexpected error recording the storage: %s", err)
+	}
+	server.Close()
+
+	// Replay them without the gateway.
+	replay := feedertest.NewReplayClient(feedertest.Dir(dir))
+	got, err := replay.GetBlock(ctx, feeder.BlockNumber(1))
+	if err != nil {
+		t.Fatalf("unexpected error replaying a block: %s", err)
+	}
+	if got.BlockHash != want.BlockHash || len(got.Transactions) != len(want.Transactions) {
+		t.Errorf("unexpected replayed block %+v, want %+v", got, want)
+	}
+	_, err = replay.GetBlock(ctx, feeder.BlockNumber(2))
+	var gatewayErr *feeder.Error
+	if !errors.As(err, &gatewayErr) || gatewayErr.Code != "StarknetErrorCode.BLOCK_NOT_FOUND" {
+		t.Errorf("unexpected error %v replaying a missing block", err)
+	}
+	value, err := replay.GetStorageAt(ctx, "0xc", "0x5", feeder.LatestBlock)
+	if err != nil {
+		t.Fatalf("unexpected error replaying the storage: %s", err)
+	}
+	if *value != "0x22c" {
+		t.Errorf("unexpected replayed storage value %s, want 0x22c", *value)
+	}
+}
+
+// transientSource fails with a transient error the first time each request is
+// made.
+type transientSource struct {
+	feedertest.Source
+	calls int32
+}
+
+func (s *transientSource) Get(endpoint string, query url.Values) ([]byte, error) {
+	if atomic.AddInt32(&s.calls, 1)%2 == 1 {
+		return nil, &feeder.Error{StatusCode: http.StatusServiceUnavailable}
+	}
+	return s.Source.Get(endpoint, query)
+}
+
+func TestRecordSkipsTransientErrors(t *testing.T) {
+	server := feedertest.NewServer(&transientSource{Source: newChain()})
+	defer server.Close()
+	dir := t.TempDir()
+
+	var recorder feeder.HttpClient = feedertest.NewRecorder(server.Client(), dir)
+	client := feeder.NewClient(server.URL, "/feeder_gateway", &recorder)
+	client.RateLimiter = nil
+	client.Retry.MinBackoff, client.Retry.MaxBackoff = 0, 0
+	if _, err := client.GetBlock(context.Background(), feeder.BlockNumber(0)); err != nil {
+		t.Fatalf("unexpected error recording a block: %s", err)
+	}
+
+	fixtures, err := filepath.Glob(filepath.Join(dir, "get_block", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) != 1 {
+		t.Fatalf("unexpected fixtures %v, want a single one", fixtures)
+	}
+	data, err := os.ReadFile(fixtures[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(fixtures[0]) != "blockNumber=0.json" || len(data) == 0 {
+		t.Errorf("unexpected fixture %s with content %s", fixtures[0], data)
+	}
+}
@@ -0,0 +1,102 @@
+package feeder
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/NethermindEth/juno/internal/log"
+)
+
+// Transaction types used by the gateway.
+const (
+	invokeFunctionType = "INVOKE_FUNCTION"
+	deployType         = "DEPLOY"
+)
+
+// GatewayClient represents a client for the StarkNet gateway, the service
+// that receives the transactions sent to the network. It limits the rate of
+// requests like the feeder gateway Client, but submitting a transaction is not
+// idempotent, so a failed request is only retried when the gateway certainly
+// didn't process it.
+type GatewayClient struct {
+	client *Client
+}
+
+// NewGatewayClient returns a new GatewayClient. The baseURL is the URL of the
+// network, the same used for the feeder gateway Client.
+func NewGatewayClient(baseURL string, client *HttpClient) *GatewayClient {
+	return &GatewayClient{client: NewClient(baseURL, "/gateway", client)}
+}
+
+// invokeRequest is the body of an add_transaction request for an invoke
+// transaction.
+type invokeRequest struct {
+	Type string `json:"type"`
+	InvokeFunction
+}
+
+// deployRequest is the body of an add_transaction request for a deploy
+// transaction.
+type deployRequest struct {
+	Type                string              `json:"type"`
+	ContractAddressSalt string              `json:"contract_address_salt"`
+	ConstructorCalldata []string            `json:"constructor_calldata"`
+	ContractDefinition  *ContractDefinition `json:"contract_definition"`
+}
+
+// AddInvokeTransaction submits a transaction that invokes the given function.
+func (c GatewayClient) AddInvokeTransaction(ctx context.Context, invokeFunc InvokeFunction) (*AddTransactionResponse, error) {
+	// The gateway rejects null lists.
+	if invokeFunc.Calldata == nil {
+		invokeFunc.Calldata = []string{}
+	}
+	if invokeFunc.Signature == nil {
+		invokeFunc.Signature = []string{}
+	}
+	return c.addTransaction(ctx, invokeRequest{Type: invokeFunctionType, InvokeFunction: invokeFunc})
+}
+
+// AddDeployTransaction submits a transaction that deploys the contract with
+// the given definition. The salt and the constructor calldata are taken from
+// the ContractAddressSalt and ConstructorCalldata fields of deploy.
+func (c GatewayClient) AddDeployTransaction(ctx context.Context, deploy TxnSpecificInfo, contractDefinition *ContractDefinition) (*AddTransactionResponse, error) {
+	constructorCalldata := deploy.ConstructorCalldata
+	if constructorCalldata == nil {
+		constructorCalldata = []string{}
+	}
+	return c.addTransaction(ctx, deployRequest{
+		Type:                deployType,
+		ContractAddressSalt: deploy.ContractAddressSalt,
+		ConstructorCalldata: constructorCalldata,
+		ContractDefinition:  contractDefinition,
+	})
+}
+
+// addTransaction submits a transaction with the given body.
+func (c GatewayClient) addTransaction(ctx context.Context, body any) (*AddTransactionResponse, error) {
+	req, err := c.client.newRequest(ctx, "POST", "/add_transaction", nil, body)
+	if err != nil {
+		log.Default.With("Error", err, "Gateway URL", c.client.BaseURL).Error("Unable to create a request for add_transaction.")
+		return nil, err
+	}
+	var res AddTransactionResponse
+	_, err = c.client.doRetrying(req, &res, notProcessed)
+	if err != nil {
+		log.Default.With("Error", err, "Gateway URL", c.client.BaseURL).Error("Error connecting to the gateway.")
+		return nil, err
+	}
+	return &res, err
+}
+
+// notProcessed reports whether the gateway rejected the request of a failed
+// attempt without processing it, so it can be sent again without submitting
+// the transaction twice. That is only certain for the requests rejected by the
+// rate limit with a Retry-After header, as a network error or a server error
+// may happen after the gateway accepted the transaction.
+func notProcessed(res *http.Response, _ error) bool {
+	if res == nil || res.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	_, ok := retryAfter(res)
+	return ok
+}
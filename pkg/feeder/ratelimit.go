@@ -0,0 +1,78 @@
+package feeder
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket rate limiter. The bucket starts full and is
+// refilled at a fixed rate up to its capacity; each request takes a token,
+// waiting for the bucket to be refilled if it's empty.
+type RateLimiter struct {
+	mu sync.Mutex
+	// rate is the number of tokens added to the bucket per second.
+	rate float64
+	// burst is the capacity of the bucket.
+	burst float64
+	// tokens is the number of tokens in the bucket. It's negative when
+	// there are requests waiting for a token.
+	tokens float64
+	// last is the last time the bucket was refilled.
+	last time.Time
+}
+
+// NewRateLimiter returns a new RateLimiter that allows rate requests per
+// second, with bursts of at most burst requests. If rate is not positive,
+// the requests are not limited.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available and takes it. If the context is done
+// before, the token is given back and the error of the context is returned.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a token from the bucket and returns the time to wait until
+// the token is available.
+func (l *RateLimiter) reserve() time.Duration {
+	if l.rate <= 0 {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
@@ -0,0 +1,73 @@
+package feeder
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy configures how the requests that fail with a transient error are
+// retried. The time between attempts grows exponentially from MinBackoff up to
+// MaxBackoff, with a random jitter so many clients don't retry at the same
+// time. If the gateway answers with a Retry-After header, its value is used
+// instead.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of retries after the first attempt.
+	// Zero disables the retries.
+	MaxRetries int
+	// MinBackoff is the time to wait before the first retry.
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum time to wait between two attempts.
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is the RetryPolicy used by the clients created with
+// NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 5,
+	MinBackoff: 500 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// backoff returns the time to wait before the given retry (starting at 0). The
+// returned value is a random duration between the half and the whole of the
+// exponential backoff.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MaxBackoff
+	// Avoid overflowing the shift for large retry numbers.
+	if retry < 32 {
+		if exp := p.MinBackoff << retry; exp > 0 && exp < p.MaxBackoff {
+			d = exp
+		}
+	}
+	half := int64(d / 2)
+	if half <= 0 {
+		return d
+	}
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+// retryAfter returns the time to wait requested by the Retry-After header of
+// the given response, which can be given in seconds or as an HTTP date.
+// Returns false if the response has no valid Retry-After header.
+func retryAfter(res *http.Response) (time.Duration, bool) {
+	if res == nil {
+		return 0, false
+	}
+	value := res.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		d := time.Until(date)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
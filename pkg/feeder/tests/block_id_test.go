@@ -0,0 +1,34 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feederfakes"
+)
+
+func TestBlockIDQuery(t *testing.T) {
+	tests := [...]struct {
+		block feeder.BlockID
+		query string
+	}{
+		{feeder.LatestBlock, "blockNumber=latest"},
+		{feeder.BlockID{}, "blockNumber=latest"},
+		{feeder.PendingBlock, "blockNumber=pending"},
+		{feeder.BlockNumber(0), "blockNumber=0"},
+		{feeder.BlockNumber(1234), "blockNumber=1234"},
+		{feeder.BlockHash("0xabc"), "blockHash=0xabc"},
+	}
+	for _, test := range tests {
+		fake := &feederfakes.FakeHttpClient{}
+		fake.DoReturns(generateResponse("{}"), nil)
+		if _, err := newRetryClient(fake).GetBlock(context.Background(), test.block); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		req := fake.DoArgsForCall(0)
+		if req.URL.RawQuery != test.query {
+			t.Errorf("query of block %s: want %q, got %q", test.block, test.query, req.URL.RawQuery)
+		}
+	}
+}
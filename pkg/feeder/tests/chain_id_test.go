@@ -0,0 +1,32 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+func TestNetworkChainID(t *testing.T) {
+	tests := [...]struct {
+		gateway string
+		want    feeder.ChainID
+		ok      bool
+	}{
+		{"https://alpha-mainnet.starknet.io", feeder.Mainnet, true},
+		{"https://alpha-mainnet.starknet.io/", feeder.Mainnet, true},
+		{"http://alpha4.starknet.io", feeder.Testnet, true},
+		{"http://localhost:8080", "", false},
+		{"://invalid", "", false},
+	}
+	for _, test := range tests {
+		got, ok := feeder.NetworkChainID(test.gateway)
+		if got != test.want || ok != test.ok {
+			t.Errorf("NetworkChainID(%q) = %q, %v, want %q, %v", test.gateway, got, ok, test.want, test.ok)
+		}
+	}
+	// The mainnet must not be mistaken for the testnet, which has more
+	// legacy blocks whose hashes are not verified.
+	if chainID, _ := feeder.NetworkChainID("https://alpha-mainnet.starknet.io"); chainID.LegacyBlocks() != 833 {
+		t.Errorf("unexpected legacy blocks %d of the mainnet, want 833", chainID.LegacyBlocks())
+	}
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/keccak"
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+func TestClassHash(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		// hinted is the JSON of the ABI and the program of the definition as
+		// encoded by cairo-lang to compute the hinted class hash.
+		hinted string
+	}{
+		{
+			name: "without attributes",
+			definition: `{
+				"abi": [{"inputs": [], "name": "f", "outputs": [], "type": "function"}],
+				"entry_points_by_type": {
+					"CONSTRUCTOR": [{"offset": "0x1", "selector": "0x2"}],
+					"EXTERNAL": [{"offset": "0x3", "selector": "0x4"}],
+					"L1_HANDLER": []
+				},
+				"program": {
+					"attributes": [],
+					"builtins": ["pedersen"],
+					"data": ["0x5", "0x6"],
+					"debug_info": {"file_contents": {}, "instruction_locations": {}},
+					"hints": {
+						"0": [
+							{
+								"accessible_scopes": ["a"],
+								"code": "x = \"é\" + '\\\\'\n",
+								"flow_tracking_data": {"ap_tracking": {"group": 0, "offset": 0}, "reference_ids": {}}
+							}
+						]
+					},
+					"identifiers": {
+						"a.BOUND": {"type": "const", "value": -106710729501573572985208420194530329073740042555888586719489}
+					},
+					"main_scope": "__main__",
+					"prime": "0x800000000000011000000000000000000000000000000000000000000000001"
+				}
+			}`,
+			hinted: `{"abi": [{"inputs": [], "name": "f", "outputs": [], "type": "function"}], ` +
+				`"program": {"builtins": ["pedersen"], "data": ["0x5", "0x6"], "debug_info": null, ` +
+				`"hints": {"0": [{"accessible_scopes": ["a"], "code": "x = \"\u00e9\" + '\\\\'\n", ` +
+				`"flow_tracking_data": {"ap_tracking": {"group": 0, "offset": 0}, "reference_ids": {}}}]}, ` +
+				`"identifiers": {"a.BOUND": {"type": "const", "value": -106710729501573572985208420194530329073740042555888586719489}}, ` +
+				`"main_scope": "__main__", "prime": "0x800000000000011000000000000000000000000000000000000000000000001"}}`,
+		},
+		{
+			name: "with attributes",
+			definition: `{
+				"abi": [],
+				"entry_points_by_type": {
+					"CONSTRUCTOR": [],
+					"EXTERNAL": [{"offset": "0x3", "selector": "0x4"}],
+					"L1_HANDLER": [{"offset": "0x1", "selector": "0x2"}]
+				},
+				"program": {
+					"attributes": [
+						{"accessible_scopes": [], "end_pc": 2, "flow_tracking_data": null, "name": "error_message", "start_pc": 1, "value": "🐺"},
+						{"accessible_scopes": ["a"], "end_pc": 4, "name": "error_message", "start_pc": 3, "value": "<&>"}
+					],
+					"builtins": [],
+					"data": ["0x7"],
+					"debug_info": null,
+					"main_scope": "__main__"
+				}
+			}`,
+			hinted: `{"abi": [], "program": {"attributes": [` +
+				`{"end_pc": 2, "name": "error_message", "start_pc": 1, "value": "\ud83d\udc3a"}, ` +
+				`{"accessible_scopes": ["a"], "end_pc": 4, "name": "error_message", "start_pc": 3, "value": "<&>"}], ` +
+				`"builtins": [], "data": ["0x7"], "debug_info": null, "main_scope": "__main__"}}`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var definition feeder.ContractDefinition
+			if err := json.Unmarshal([]byte(test.definition), &definition); err != nil {
+				t.Fatalf("unexpected error decoding the contract definition: %s", err)
+			}
+			entryPoints := func(entryPoints []feeder.EntryPoint) *big.Int {
+				var data []*big.Int
+				for _, entryPoint := range entryPoints {
+					selector, _ := new(big.Int).SetString(entryPoint.Selector[2:], 16)
+					offset, _ := new(big.Int).SetString(entryPoint.Offset[2:], 16)
+					data = append(data, selector, offset)
+				}
+				return pedersen.ArrayDigest(data...)
+			}
+			var builtins, bytecode []*big.Int
+			for _, builtin := range definition.Program.Builtins {
+				builtins = append(builtins, ascii(builtin))
+			}
+			for _, data := range definition.Program.Data {
+				felt, _ := new(big.Int).SetString(data[2:], 16)
+				bytecode = append(bytecode, felt)
+			}
+			want := pedersen.ArrayDigest(
+				new(big.Int),
+				entryPoints(definition.EntryPointsByType.External),
+				entryPoints(definition.EntryPointsByType.L1Handler),
+				entryPoints(definition.EntryPointsByType.Constructor),
+				pedersen.ArrayDigest(builtins...),
+				keccak.Digest250([]byte(test.hinted)),
+				pedersen.ArrayDigest(bytecode...),
+			)
+
+			got, err := definition.ClassHash()
+			if err != nil {
+				t.Fatalf("unexpected error computing the class hash: %s", err)
+			}
+			if got.Cmp(want) != 0 {
+				t.Errorf("unexpected class hash %x, want %x", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// edgeHash returns the hash of an edge node of the given length with a zero
+// path whose bottom has the given hash.
+func edgeHash(bottom *big.Int, length int64) *big.Int {
+	h := pedersen.Digest(bottom, new(big.Int))
+	return h.Add(h, big.NewInt(length))
+}
+
+func TestCommitments(t *testing.T) {
+	config := feeder.StarknetGeneralConfig{
+		TxCommitmentTreeHeight:    feeder.CommitmentTreeHeight,
+		EventCommitmentTreeHeight: feeder.CommitmentTreeHeight,
+	}
+	b := &feeder.StarknetBlock{
+		Transactions: []feeder.TxnSpecificInfo{
+			{TransactionHash: "0x10", Type: "DEPLOY"},
+			{TransactionHash: "0x20", Signature: []string{"0x1", "0x2"}, Type: "INVOKE_FUNCTION"},
+		},
+		TransactionReceipts: []feeder.TransactionExecution{
+			{TransactionHash: "0x10"},
+			{
+				TransactionHash: "0x20",
+				Events:          []feeder.Event{{FromAddress: "0xc", Keys: []string{"0x1"}, Data: []string{"0x22b"}}},
+			},
+		},
+	}
+
+	// The transactions are at the keys 0 and 1, so the root is an edge node
+	// of length 63 over the binary node of both leaves.
+	deploy := pedersen.Digest(big.NewInt(0x10), pedersen.ArrayDigest())
+	invoke := pedersen.Digest(big.NewInt(0x20), pedersen.ArrayDigest(big.NewInt(1), big.NewInt(2)))
+	want := edgeHash(pedersen.Digest(deploy, invoke), 63)
+	if got := b.TransactionCommitment(config); got.Cmp(want) != 0 {
+		t.Errorf("unexpected transaction commitment %x, want %x", got, want)
+	}
+
+	// The only event is at the key 0, so the root is an edge node of length
+	// 64 over its leaf.
+	event := pedersen.ArrayDigest(
+		big.NewInt(0xc), pedersen.ArrayDigest(big.NewInt(1)), pedersen.ArrayDigest(big.NewInt(0x22b)),
+	)
+	want = edgeHash(event, 64)
+	if got := b.EventCommitment(config); got.Cmp(want) != 0 {
+		t.Errorf("unexpected event commitment %x, want %x", got, want)
+	}
+
+	empty := &feeder.StarknetBlock{}
+	if got := empty.TransactionCommitment(config); got.Sign() != 0 {
+		t.Errorf("unexpected transaction commitment %x of an empty block", got)
+	}
+	if got := empty.EventCommitment(config); got.Sign() != 0 {
+		t.Errorf("unexpected event commitment %x of an empty block", got)
+	}
+}
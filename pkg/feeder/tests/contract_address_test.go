@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+func TestContractAddress(t *testing.T) {
+	deployer := big.NewInt(0xd)
+	salt := big.NewInt(0x5a17)
+	classHash, _ := new(big.Int).SetString("10455c752b86932ce552f2b0fe81a880746649b9aee7e0d842bf3f52378f9f8", 16)
+	calldata := []*big.Int{big.NewInt(1), big.NewInt(2)}
+
+	want := pedersen.ArrayDigest(
+		ascii("STARKNET_CONTRACT_ADDRESS"), deployer, salt, classHash, pedersen.ArrayDigest(calldata...),
+	)
+	// The hash is reduced modulo 2²⁵¹ - 256, which only changes it if it's
+	// not below the bound.
+	bound := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 251), big.NewInt(256))
+	want.Mod(want, bound)
+	if got := feeder.ContractAddress(deployer, salt, classHash, calldata); got.Cmp(want) != 0 {
+		t.Errorf("unexpected address %x, want %x", got, want)
+	}
+
+	// The address of a contract deployed by a deploy transaction has a zero
+	// deployer address.
+	if feeder.ContractAddress(new(big.Int), salt, classHash, calldata).Cmp(want) == 0 {
+		t.Errorf("the address doesn't depend on the deployer address")
+	}
+	if feeder.ContractAddress(deployer, salt, classHash, calldata[:1]).Cmp(want) == 0 {
+		t.Errorf("the address doesn't depend on the constructor calldata")
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/stretchr/testify/assert"
+)
+
+// contractDefinition is a trimmed definition of a contract with a storage
+// variable and an external function, as returned by get_full_contract.
+const contractDefinition = `{
+	"abi": [
+		{"inputs": [{"name": "amount", "type": "felt"}], "name": "increase_balance", "outputs": [], "type": "function"},
+		{"inputs": [], "name": "get_balance", "outputs": [{"name": "res", "type": "felt"}], "stateMutability": "view", "type": "function"}
+	],
+	"entry_points_by_type": {
+		"CONSTRUCTOR": [],
+		"EXTERNAL": [
+			{"offset": "0x3a", "selector": "0x362398bec32bc0ebb411203221a35a0301193a96f317ebe5e40be9f60d15320"},
+			{"offset": "0x5b", "selector": "0x39e11d48192e4333233c7eb19d10ad67c362bb28580c604d67884c85da39695"}
+		],
+		"L1_HANDLER": []
+	},
+	"program": {
+		"attributes": [],
+		"builtins": ["pedersen", "range_check"],
+		"compiler_version": "0.9.0",
+		"data": ["0x40780017fff7fff", "0x1", "0x208b7fff7fff7ffe"],
+		"debug_info": null,
+		"hints": {
+			"0": [
+				{
+					"accessible_scopes": ["starkware.cairo.common.alloc", "starkware.cairo.common.alloc.alloc"],
+					"code": "memory[ap] = segments.add()",
+					"flow_tracking_data": {"ap_tracking": {"group": 0, "offset": 0}, "reference_ids": {}}
+				}
+			]
+		},
+		"identifiers": {
+			"__main__.balance": {"type": "namespace"},
+			"__main__.balance.addr": {"decorators": [], "pc": 0, "type": "function"},
+			"__main__.balance.addr.Args": {"full_name": "__main__.balance.addr.Args", "members": {}, "size": 0, "type": "struct"},
+			"__main__.HashBuiltin": {"destination": "starkware.cairo.common.cairo_builtins.HashBuiltin", "type": "alias"},
+			"starkware.cairo.common.cairo_builtins.HashBuiltin": {
+				"full_name": "starkware.cairo.common.cairo_builtins.HashBuiltin",
+				"members": {
+					"result": {"cairo_type": "felt", "offset": 2},
+					"x": {"cairo_type": "felt", "offset": 0},
+					"y": {"cairo_type": "felt", "offset": 1}
+				},
+				"size": 3,
+				"type": "struct"
+			},
+			"starkware.cairo.common.storage.ADDR_BOUND": {
+				"type": "const",
+				"value": -106710729501573572985208420194530329073740042555888586719489
+			},
+			"__main__.increase_balance.amount": {
+				"cairo_type": "felt",
+				"full_name": "__main__.increase_balance.amount",
+				"references": [
+					{"ap_tracking_data": {"group": 5, "offset": 0}, "pc": 43, "value": "[cast(fp + (-3), felt*)]"}
+				],
+				"type": "reference"
+			}
+		},
+		"main_scope": "__main__",
+		"prime": "0x800000000000011000000000000000000000000000000000000000000000001",
+		"reference_manager": {
+			"references": [
+				{"ap_tracking_data": {"group": 5, "offset": 0}, "pc": 43, "value": "[cast(fp + (-3), felt*)]"}
+			]
+		}
+	}
+}`
+
+func TestContractDefinition(t *testing.T) {
+	var definition feeder.ContractDefinition
+	if err := json.Unmarshal([]byte(contractDefinition), &definition); err != nil {
+		t.Fatalf("unexpected error decoding the contract definition: %s", err)
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		assert.Len(t, definition.EntryPointsByType.External, 2)
+		assert.Equal(t, "0x3a", definition.EntryPointsByType.External[0].Offset)
+		assert.Empty(t, definition.EntryPointsByType.Constructor)
+		assert.Equal(t, []string{"pedersen", "range_check"}, definition.Program.Builtins)
+		assert.Len(t, definition.Program.Data, 3)
+		assert.Equal(t, "memory[ap] = segments.add()", definition.Program.Hints["0"][0].Code)
+		assert.Equal(t, "0x800000000000011000000000000000000000000000000000000000000000001", definition.Program.Prime)
+		assert.Len(t, definition.Program.ReferenceManager.References, 1)
+
+		hashBuiltin := definition.Program.Identifiers["starkware.cairo.common.cairo_builtins.HashBuiltin"]
+		assert.Equal(t, uint64(3), hashBuiltin.Size)
+		assert.Equal(t, uint64(2), hashBuiltin.Members["result"].Offset)
+		bound := definition.Program.Identifiers["starkware.cairo.common.storage.ADDR_BOUND"]
+		assert.Equal(t, "-106710729501573572985208420194530329073740042555888586719489", bound.Value.String())
+		amount := definition.Program.Identifiers["__main__.increase_balance.amount"]
+		assert.Equal(t, uint64(43), amount.References[0].Pc)
+		assert.Equal(t, uint64(5), amount.References[0].ApTrackingData.Group)
+
+		parsed, err := definition.Abi.Parse()
+		if err != nil {
+			t.Fatalf("unexpected error parsing the ABI: %s", err)
+		}
+		assert.Len(t, parsed.Functions, 2)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		encoded, err := json.Marshal(definition)
+		if err != nil {
+			t.Fatalf("unexpected error encoding the contract definition: %s", err)
+		}
+		assert.JSONEq(t, contractDefinition, string(encoded))
+	})
+
+	t.Run("round trip after changes", func(t *testing.T) {
+		changed := definition
+		changed.Program.Data = append([]string{}, definition.Program.Data...)
+		changed.Program.Data[0] = "0x1"
+		encoded, err := json.Marshal(changed)
+		if err != nil {
+			t.Fatalf("unexpected error encoding the contract definition: %s", err)
+		}
+		var members struct {
+			Program map[string]json.RawMessage `json:"program"`
+		}
+		if err := json.Unmarshal(encoded, &members); err != nil {
+			t.Fatal(err)
+		}
+		assert.JSONEq(t, `["0x1", "0x1", "0x208b7fff7fff7ffe"]`, string(members.Program["data"]))
+		assert.JSONEq(t, `"0.9.0"`, string(members.Program["compiler_version"]))
+		assert.JSONEq(t, `null`, string(members.Program["debug_info"]))
+	})
+}
@@ -6,6 +6,7 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -69,7 +70,7 @@ func TestGetContractAddress(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	contractAddresses, err := client.GetContractAddresses()
+	contractAddresses, err := client.GetContractAddresses(context.Background())
 	if err != nil {
 		t.Fatal()
 		return
@@ -82,7 +83,7 @@ func TestCallContract(t *testing.T) {
 	body, err := json.Marshal(a)
 
 	httpClient.DoReturns(generateResponse(string(body)), nil)
-	contractResponse, err := client.CallContract(feeder.InvokeFunction{}, "", "latest")
+	contractResponse, err := client.CallContract(context.Background(), feeder.InvokeFunction{}, feeder.LatestBlock)
 	if err != nil {
 		t.Fatal()
 	}
@@ -96,7 +97,7 @@ func TestGetBlock(t *testing.T) {
 		t.Fatal()
 	}
 	httpClient.DoReturns(generateResponse(body), nil)
-	starknetBlock, err := client.GetBlock("", "latest")
+	starknetBlock, err := client.GetBlock(context.Background(), feeder.LatestBlock)
 	if err != nil {
 		t.Fatal()
 	}
@@ -112,7 +113,7 @@ func TestGetStateUpdate(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	getStateUpdate, err := client.GetStateUpdate("hash", "")
+	getStateUpdate, err := client.GetStateUpdate(context.Background(), feeder.BlockHash("hash"))
 	if err != nil {
 		t.Fatal()
 	}
@@ -120,18 +121,16 @@ func TestGetStateUpdate(t *testing.T) {
 }
 
 func TestGetFullContract(t *testing.T) {
-	body := "{\"block_hash\": \"0x03a0ae1aaefeed60bafd6990f06d0b68fb593b5d9395ff726868ee61a6e1beb3\", \"block_number\": \"3\"}\n"
-	httpClient.DoReturns(generateResponse(body), nil)
-	var cOrig map[string]interface{}
-	err := json.Unmarshal([]byte(body), &cOrig)
+	httpClient.DoReturns(generateResponse(contractDefinition), nil)
+	definition, err := client.GetFullContract(context.Background(), "address", feeder.BlockHash("hash"))
 	if err != nil {
 		t.Fatal()
 	}
-	getStateUpdate, err := client.GetFullContract("address", "hash", "number")
+	encoded, err := json.Marshal(definition)
 	if err != nil {
 		t.Fatal()
 	}
-	assert.Equal(t, cOrig, getStateUpdate, "State Update response don't match")
+	assert.JSONEq(t, contractDefinition, string(encoded), "Full contract response don't match")
 }
 
 func TestGetCode(t *testing.T) {
@@ -141,7 +140,7 @@ func TestGetCode(t *testing.T) {
 		t.Fatal()
 	}
 	httpClient.DoReturns(generateResponse(body), nil)
-	getCode, err := client.GetCode("hash", "", "latest")
+	getCode, err := client.GetCode(context.Background(), "hash", feeder.LatestBlock)
 	if err != nil {
 		t.Fatal()
 	}
@@ -175,7 +174,7 @@ func TestGetTransaction(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionInfo, err := client.GetTransaction("", "id")
+	transactionInfo, err := client.GetTransaction(context.Background(), "", "id")
 	if err != nil {
 		t.Fatal()
 	}
@@ -198,7 +197,7 @@ func TestGetTransactionbyHash(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionInfo, err := client.GetTransaction("hash", "id")
+	transactionInfo, err := client.GetTransaction(context.Background(), "hash", "id")
 	if err != nil {
 		t.Fatal()
 	}
@@ -221,7 +220,7 @@ func TestGetTransactionReceipt(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionReceipt, err := client.GetTransactionReceipt("", "id")
+	transactionReceipt, err := client.GetTransactionReceipt(context.Background(), "", "id")
 	if err != nil {
 		t.Fatal()
 	}
@@ -244,7 +243,7 @@ func TestGetTransactionStatus(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionStatus, err := client.GetTransactionStatus("", "id")
+	transactionStatus, err := client.GetTransactionStatus(context.Background(), "", "id")
 	if err != nil {
 		t.Fatal()
 	}
@@ -259,7 +258,7 @@ func TestGetBlockHashById(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	blockHash, err := client.GetBlockHashById("id")
+	blockHash, err := client.GetBlockHashById(context.Background(), "id")
 	if err != nil {
 		t.Fatal()
 	}
@@ -274,7 +273,7 @@ func TestGetBlockIdByHash(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	blockId, err := client.GetBlockIDByHash("hash")
+	blockId, err := client.GetBlockIDByHash(context.Background(), "hash")
 	if err != nil {
 		t.Fatal()
 	}
@@ -289,7 +288,7 @@ func TestGetTransactionHashById(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionHash, err := client.GetTransactionHashByID("hash")
+	transactionHash, err := client.GetTransactionHashByID(context.Background(), "hash")
 	if err != nil {
 		t.Fatal()
 	}
@@ -304,7 +303,7 @@ func TestGetTransactionIdByHash(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionId, err := client.GetTransactionIDByHash("hash")
+	transactionId, err := client.GetTransactionIDByHash(context.Background(), "hash")
 	if err != nil {
 		t.Fatal()
 	}
@@ -320,7 +319,7 @@ func TestGetStorageAt(t *testing.T) {
 	if err != nil {
 		t.Fatal()
 	}
-	transactionId, err := client.GetStorageAt("address", "key", "hash", "")
+	transactionId, err := client.GetStorageAt(context.Background(), "address", "key", feeder.BlockHash("hash"))
 	if err != nil {
 		t.Fatal()
 	}
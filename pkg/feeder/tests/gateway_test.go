@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feederfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+// newGatewayClient returns a gateway client that uses the given fake http
+// client.
+func newGatewayClient(httpClient *feederfakes.FakeHttpClient) *feeder.GatewayClient {
+	var p feeder.HttpClient = httpClient
+	return feeder.NewGatewayClient("https:/local", &p)
+}
+
+// requestBody returns the decoded body of the request made with the given
+// fake http client.
+func requestBody(t *testing.T, httpClient *feederfakes.FakeHttpClient) (*http.Request, map[string]interface{}) {
+	req := httpClient.DoArgsForCall(0)
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("unexpected error decoding the request body %s: %s", data, err)
+	}
+	return req, body
+}
+
+func TestAddInvokeTransaction(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoReturns(generateResponse(`{"code": "TRANSACTION_RECEIVED", "transaction_hash": "0x1234"}`), nil)
+
+	res, err := newGatewayClient(fake).AddInvokeTransaction(context.Background(), feeder.InvokeFunction{
+		ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+		EntryPointSelector: "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+		Calldata:           []string{"0x5", "0x22b"},
+		MaxFee:             "0x0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, &feeder.AddTransactionResponse{Code: "TRANSACTION_RECEIVED", TransactionHash: "0x1234"}, res)
+
+	req, body := requestBody(t, fake)
+	assert.Equal(t, "POST", req.Method)
+	assert.Equal(t, "/gateway/add_transaction", req.URL.Path)
+	assert.Equal(t, map[string]interface{}{
+		"type":                 "INVOKE_FUNCTION",
+		"contract_address":     "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+		"entry_point_selector": "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+		"calldata":             []interface{}{"0x5", "0x22b"},
+		"signature":            []interface{}{},
+		"max_fee":              "0x0",
+	}, body)
+}
+
+func TestAddDeployTransaction(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoReturns(generateResponse(`{"code": "TRANSACTION_RECEIVED", "transaction_hash": "0x5678", "address": "0xabc"}`), nil)
+
+	var definition feeder.ContractDefinition
+	if err := json.Unmarshal([]byte(contractDefinition), &definition); err != nil {
+		t.Fatal(err)
+	}
+	res, err := newGatewayClient(fake).AddDeployTransaction(context.Background(), feeder.TxnSpecificInfo{
+		ContractAddressSalt: "0x1",
+		ConstructorCalldata: []string{"0x2"},
+	}, &definition)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, &feeder.AddTransactionResponse{Code: "TRANSACTION_RECEIVED", TransactionHash: "0x5678", Address: "0xabc"}, res)
+
+	_, body := requestBody(t, fake)
+	assert.Equal(t, "DEPLOY", body["type"])
+	assert.Equal(t, "0x1", body["contract_address_salt"])
+	assert.Equal(t, []interface{}{"0x2"}, body["constructor_calldata"])
+	sentDefinition, err := json.Marshal(body["contract_definition"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, contractDefinition, string(sentDefinition))
+}
+
+func TestAddTransactionRejected(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoReturns(generateErrorResponse(http.StatusBadRequest,
+		`{"code": "StarknetErrorCode.UNINITIALIZED_CONTRACT", "message": "Requested contract address 0x1 is not deployed."}`), nil)
+
+	_, err := newGatewayClient(fake).AddInvokeTransaction(context.Background(), feeder.InvokeFunction{ContractAddress: "0x1"})
+	gatewayErr, ok := err.(*feeder.Error)
+	if !ok {
+		t.Fatalf("unexpected error %v, want a *feeder.Error", err)
+	}
+	assert.Equal(t, "StarknetErrorCode.UNINITIALIZED_CONTRACT", gatewayErr.Code)
+	assert.Equal(t, 1, fake.DoCallCount(), "rejected transactions must not be retried")
+}
+
+func TestAddTransactionNotRetried(t *testing.T) {
+	// The gateway may have accepted the transaction before failing, so it is
+	// not submitted again.
+	for name, fail := range map[string]func(*feederfakes.FakeHttpClient){
+		"server error": func(fake *feederfakes.FakeHttpClient) {
+			fake.DoReturns(generateErrorResponse(http.StatusServiceUnavailable, ""), nil)
+		},
+		"network error": func(fake *feederfakes.FakeHttpClient) {
+			fake.DoReturns(nil, errors.New("connection reset by peer"))
+		},
+		"rate limit without retry-after": func(fake *feederfakes.FakeHttpClient) {
+			fake.DoReturns(generateErrorResponse(http.StatusTooManyRequests, ""), nil)
+		},
+	} {
+		fake := &feederfakes.FakeHttpClient{}
+		fail(fake)
+		if _, err := newGatewayClient(fake).AddInvokeTransaction(context.Background(), feeder.InvokeFunction{}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		assert.Equal(t, 1, fake.DoCallCount(), "%s: the transaction must be submitted once", name)
+	}
+}
+
+func TestAddTransactionRateLimited(t *testing.T) {
+	// The requests rejected by the rate limit with a Retry-After header were
+	// not processed, so they are sent again.
+	fake := &feederfakes.FakeHttpClient{}
+	limited := generateErrorResponse(http.StatusTooManyRequests, "")
+	limited.Header.Set("Retry-After", "0")
+	fake.DoReturnsOnCall(0, limited, nil)
+	fake.DoReturnsOnCall(1, generateResponse(`{"code": "TRANSACTION_RECEIVED", "transaction_hash": "0x1"}`), nil)
+
+	res, err := newGatewayClient(fake).AddInvokeTransaction(context.Background(), feeder.InvokeFunction{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "TRANSACTION_RECEIVED", res.Code)
+	assert.Equal(t, 2, fake.DoCallCount())
+}
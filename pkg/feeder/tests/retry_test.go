@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
+	"github.com/NethermindEth/juno/pkg/feeder/feederfakes"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRetryClient returns a client that uses the given fake http client and
+// retries without waiting between attempts.
+func newRetryClient(httpClient *feederfakes.FakeHttpClient) *feeder.Client {
+	var p feeder.HttpClient = httpClient
+	c := feeder.NewClient("https:/local", "/feeder_gateway/", &p)
+	c.Retry = feeder.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	c.RateLimiter = nil
+	return c
+}
+
+func generateErrorResponse(statusCode int, body string) *http.Response {
+	res := generateResponse(body)
+	res.StatusCode = statusCode
+	res.Status = http.StatusText(statusCode)
+	return res
+}
+
+func TestRetryTransientErrors(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoReturnsOnCall(0, nil, errors.New("connection reset by peer"))
+	fake.DoReturnsOnCall(1, generateErrorResponse(http.StatusServiceUnavailable, "Service Unavailable"), nil)
+	fake.DoReturnsOnCall(2, generateErrorResponse(http.StatusTooManyRequests, "Too Many Requests"), nil)
+	fake.DoReturnsOnCall(3, generateResponse(`"0x1"`), nil)
+
+	hash, err := newRetryClient(fake).GetBlockHashById(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assert.Equal(t, "0x1", *hash)
+	assert.Equal(t, 4, fake.DoCallCount())
+}
+
+func TestRetryGiveUp(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoReturns(generateErrorResponse(http.StatusBadGateway, "Bad Gateway"), nil)
+
+	_, err := newRetryClient(fake).GetBlockHashById(context.Background(), "1")
+	if !errors.Is(err, feeder.ErrTransient) {
+		t.Errorf("unexpected error %v, want a transient error", err)
+	}
+	// The first attempt and three retries.
+	assert.Equal(t, 4, fake.DoCallCount())
+}
+
+func TestRetryAfter(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	res := generateErrorResponse(http.StatusTooManyRequests, "Too Many Requests")
+	res.Header.Set("Retry-After", "1")
+	fake.DoReturnsOnCall(0, res, nil)
+	fake.DoReturnsOnCall(1, generateResponse(`"0x1"`), nil)
+
+	start := time.Now()
+	if _, err := newRetryClient(fake).GetBlockHashById(context.Background(), "1"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("the client waited %s, want at least the 1s of the Retry-After header", elapsed)
+	}
+}
+
+func TestUnsuccessfulStatus(t *testing.T) {
+	// Any status but a 2xx one is an error, including none at all.
+	for _, status := range []int{0, http.StatusSwitchingProtocols, http.StatusFound} {
+		fake := &feederfakes.FakeHttpClient{}
+		fake.DoReturns(generateErrorResponse(status, `"0x1"`), nil)
+
+		_, err := newRetryClient(fake).GetBlockHashById(context.Background(), "1")
+		var gatewayErr *feeder.Error
+		if !errors.As(err, &gatewayErr) || gatewayErr.StatusCode != status {
+			t.Errorf("status %d: unexpected error %v, want a gateway error", status, err)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	// An object not found is never retried, even if the gateway answers with
+	// a server error status.
+	for _, status := range []int{http.StatusBadRequest, http.StatusInternalServerError} {
+		fake := &feederfakes.FakeHttpClient{}
+		fake.DoReturns(generateErrorResponse(status,
+			`{"code": "StarknetErrorCode.BLOCK_NOT_FOUND", "message": "Block number 1000000 was not found."}`), nil)
+
+		_, err := newRetryClient(fake).GetBlock(context.Background(), feeder.BlockNumber(1000000))
+		if !errors.Is(err, feeder.ErrNotFound) {
+			t.Errorf("status %d: unexpected error %v, want a not found error", status, err)
+		}
+		if errors.Is(err, feeder.ErrTransient) {
+			t.Errorf("status %d: unexpected transient error %v", status, err)
+		}
+		var gatewayErr *feeder.Error
+		if !errors.As(err, &gatewayErr) {
+			t.Fatalf("status %d: unexpected error type %T", status, err)
+		}
+		assert.Equal(t, "StarknetErrorCode.BLOCK_NOT_FOUND", gatewayErr.Code)
+		assert.Equal(t, 1, fake.DoCallCount())
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := feeder.NewRateLimiter(20, 2)
+	start := time.Now()
+	// The first two requests use the burst, and the next two must wait 50ms
+	// each.
+	for i := 0; i < 4; i++ {
+		limiter.Wait(context.Background())
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("4 requests took %s, want at least 100ms", elapsed)
+	}
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		limiter := feeder.NewRateLimiter(rate, 0)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 100; i++ {
+			if err := limiter.Wait(ctx); err != nil {
+				t.Fatalf("rate %v: unexpected error %v waiting for request %d", rate, err, i)
+			}
+		}
+		cancel()
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	fake := &feederfakes.FakeHttpClient{}
+	fake.DoStub = func(req *http.Request) (*http.Response, error) {
+		// Block until the request is canceled, like a gateway that does not
+		// answer.
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := newRetryClient(fake).GetBlock(ctx, feeder.BlockNumber(1))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error %v, want %v", err, context.Canceled)
+	}
+	// Canceled requests must not be retried.
+	assert.Equal(t, 1, fake.DoCallCount())
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/keccak"
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+	"github.com/NethermindEth/juno/pkg/feeder"
+)
+
+// ascii returns the field element of the ASCII encoding of s.
+func ascii(s string) *big.Int {
+	return new(big.Int).SetBytes([]byte(s))
+}
+
+// chainHash returns the hash of an array as the StarkNet specification defines
+// it, h(h(h(h(0, data[0]), data[1]), ...), len(data)) with h the Pedersen hash
+// of two elements, so that the expected hashes don't depend on the
+// implementation under test.
+func chainHash(data ...*big.Int) *big.Int {
+	hash := new(big.Int)
+	for _, x := range data {
+		hash = pedersen.Digest(hash, x)
+	}
+	return pedersen.Digest(hash, big.NewInt(int64(len(data))))
+}
+
+// toHash returns the hex representation of the given hash.
+func toHash(hash *big.Int) string {
+	return "0x" + hash.Text(16)
+}
+
+var (
+	txAddress      = big.NewInt(0xc)
+	txSelector     = keccak.Digest250([]byte("increase_balance"))
+	txCalldata     = chainHash(big.NewInt(5), big.NewInt(0x22b))
+	mainnetChainID = ascii("SN_MAIN")
+)
+
+func TestCalculateTransactionHash(t *testing.T) {
+	config := feeder.StarknetGeneralConfig{ChainID: feeder.Mainnet}
+	invoke := feeder.InvokeFunction{
+		ContractAddress:    "0xc",
+		EntryPointSelector: toHash(txSelector),
+		Calldata:           []string{"0x5", "0x22b"},
+		MaxFee:             "0x64",
+	}
+	want := toHash(chainHash(
+		ascii("invoke"), big.NewInt(0), txAddress, txSelector, txCalldata, big.NewInt(100), mainnetChainID,
+	))
+	if got := invoke.CalculateHash(config); string(got) != want {
+		t.Errorf("unexpected hash %s of the invoke function, want %s", got, want)
+	}
+	tx := feeder.TxnSpecificInfo{
+		ContractAddress:    invoke.ContractAddress,
+		EntryPointSelector: invoke.EntryPointSelector,
+		Calldata:           invoke.Calldata,
+		MaxFee:             invoke.MaxFee,
+		Type:               "INVOKE_FUNCTION",
+	}
+	if got := tx.CalculateHash(config); string(got) != want {
+		t.Errorf("unexpected hash %s of the invoke transaction, want %s", got, want)
+	}
+
+	tests := []struct {
+		name string
+		tx   feeder.TxnSpecificInfo
+		want *big.Int
+	}{
+		{
+			"deploy",
+			feeder.TxnSpecificInfo{ContractAddress: "0xc", ConstructorCalldata: []string{"0x5", "0x22b"}, Type: "DEPLOY"},
+			chainHash(
+				ascii("deploy"), big.NewInt(0), txAddress, keccak.Digest250([]byte("constructor")), txCalldata,
+				big.NewInt(0), mainnetChainID,
+			),
+		},
+		{
+			"l1 handler",
+			feeder.TxnSpecificInfo{
+				ContractAddress:    "0xc",
+				EntryPointSelector: toHash(txSelector),
+				Calldata:           []string{"0x5", "0x22b"},
+				Nonce:              "0x7",
+				Type:               "L1_HANDLER",
+			},
+			chainHash(
+				ascii("l1_handler"), big.NewInt(0), txAddress, txSelector, txCalldata, big.NewInt(0), mainnetChainID,
+				big.NewInt(7),
+			),
+		},
+	}
+	for _, test := range tests {
+		if got := test.tx.CalculateHash(config); string(got) != toHash(test.want) {
+			t.Errorf("unexpected hash %s of the %s transaction, want %s", got, test.name, toHash(test.want))
+		}
+	}
+}
+
+func TestVerifyTransactionHash(t *testing.T) {
+	invoke := feeder.TxnSpecificInfo{
+		ContractAddress:    "0xc",
+		EntryPointSelector: toHash(txSelector),
+		Calldata:           []string{"0x5", "0x22b"},
+		MaxFee:             "0x64",
+		Type:               "INVOKE_FUNCTION",
+	}
+	invoke.TransactionHash = string(invoke.CalculateHash(feeder.StarknetGeneralConfig{ChainID: feeder.Mainnet}))
+	// An invoke transaction hashed before the max fee was part of the hash.
+	deprecated := feeder.TxnSpecificInfo{
+		ContractAddress:    "0xc",
+		EntryPointSelector: toHash(txSelector),
+		Calldata:           []string{"0x5", "0x22b"},
+		TransactionHash:    toHash(chainHash(ascii("invoke"), txAddress, txSelector, txCalldata, mainnetChainID)),
+		Type:               "INVOKE_FUNCTION",
+	}
+	// An L1 handler transaction without a nonce is hashed like an old invoke
+	// transaction.
+	l1Handler := deprecated
+	l1Handler.EntryPointType = "L1_HANDLER"
+	// A deploy transaction hashed before the version was part of the hash.
+	deploy := feeder.TxnSpecificInfo{
+		ContractAddress:     "0xc",
+		ConstructorCalldata: []string{"0x5", "0x22b"},
+		TransactionHash: toHash(chainHash(
+			ascii("deploy"), txAddress, keccak.Digest250([]byte("constructor")), txCalldata, mainnetChainID,
+		)),
+		Type: "DEPLOY",
+	}
+
+	for _, tx := range []feeder.TxnSpecificInfo{invoke, deprecated, l1Handler, deploy} {
+		if err := tx.VerifyHash(feeder.Mainnet); err != nil {
+			t.Errorf("unexpected error verifying the transaction: %s", err)
+		}
+		if err := tx.VerifyHash(feeder.Testnet); !errors.Is(err, feeder.ErrInvalidTransactionHash) {
+			t.Errorf("unexpected error %v verifying the transaction in another chain", err)
+		}
+	}
+
+	tampered := invoke
+	tampered.Calldata = []string{"0x5", "0x22c"}
+	if err := tampered.VerifyHash(feeder.Mainnet); !errors.Is(err, feeder.ErrInvalidTransactionHash) {
+		t.Errorf("unexpected error %v verifying a tampered transaction", err)
+	}
+
+	block := &feeder.StarknetBlock{Transactions: []feeder.TxnSpecificInfo{invoke, deprecated}}
+	if err := feeder.VerifyTransactionHashes(block, feeder.Mainnet); err != nil {
+		t.Errorf("unexpected error verifying the block: %s", err)
+	}
+	block.Transactions = append(block.Transactions, tampered)
+	if err := feeder.VerifyTransactionHashes(block, feeder.Mainnet); !errors.Is(err, feeder.ErrInvalidTransactionHash) {
+		t.Errorf("unexpected error %v verifying a block with a tampered transaction", err)
+	}
+}
@@ -0,0 +1,175 @@
+package feeder
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/crypto/keccak"
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// ErrInvalidTransactionHash is matched by the errors returned when the hash
+// of a transaction reported by the gateway doesn't match its content. Use
+// errors.Is to check for it.
+var ErrInvalidTransactionHash = errors.New("invalid transaction hash")
+
+// The prefixes of the transaction hashes are the ASCII encoding of the type of
+// the transaction.
+var (
+	invokeHashPrefix    = new(big.Int).SetBytes([]byte("invoke"))
+	deployHashPrefix    = new(big.Int).SetBytes([]byte("deploy"))
+	l1HandlerHashPrefix = new(big.Int).SetBytes([]byte("l1_handler"))
+)
+
+// constructorSelector is the selector of the constructor, used as the
+// selector of the deploy transactions.
+var constructorSelector = keccak.Digest250([]byte("constructor"))
+
+// Felt returns the field element that represents the chain in the
+// transaction hashes: the ASCII encoding of "SN_MAIN" for the mainnet,
+// "SN_GOERLI" for the testnet and the chain ID itself for any other chain.
+func (c ChainID) Felt() *big.Int {
+	switch c {
+	case Mainnet:
+		return new(big.Int).SetBytes([]byte("SN_MAIN"))
+	case Testnet:
+		return new(big.Int).SetBytes([]byte("SN_GOERLI"))
+	default:
+		return new(big.Int).SetBytes([]byte(c))
+	}
+}
+
+// CalculateHash returns the hash of the transaction in the chain of the
+// given configuration.
+func (i InvokeFunction) CalculateHash(config StarknetGeneralConfig) Hash {
+	return toHash(transactionHash(
+		invokeHashPrefix,
+		new(big.Int),
+		feltOf(i.ContractAddress),
+		feltOf(i.EntryPointSelector),
+		feltsOf(i.Calldata),
+		feltOf(i.MaxFee),
+		config.ChainID.Felt(),
+	))
+}
+
+// TransactionType returns the TxnType of the transaction. The transactions
+// that are not deploy transactions, including the L1 handler ones, are
+// invocations of a contract function.
+func (t TxnSpecificInfo) TransactionType() TxnType {
+	if t.Type == "DEPLOY" {
+		return Deploy
+	}
+	return Invoke
+}
+
+// CalculateHash returns the hash of the transaction in the chain of the
+// given configuration. Invoke, deploy and L1 handler transactions are
+// supported.
+func (t TxnSpecificInfo) CalculateHash(config StarknetGeneralConfig) Hash {
+	return toHash(t.hashes(config.ChainID.Felt())[0])
+}
+
+// VerifyHash checks that the TransactionHash of the transaction, as reported
+// by the gateway, is the hash of its content in the given chain. The hashes
+// of the transactions sent before the version and the max fee were part of
+// the hash are accepted too.
+func (t TxnSpecificInfo) VerifyHash(chainID ChainID) error {
+	want := feltOf(t.TransactionHash)
+	hashes := t.hashes(chainID.Felt())
+	for _, hash := range hashes {
+		if hash.Cmp(want) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: transaction %s, computed %s",
+		ErrInvalidTransactionHash, t.TransactionHash, toHash(hashes[0]))
+}
+
+// VerifyTransactionHashes checks the hashes of all the transactions of the
+// block with VerifyHash.
+func VerifyTransactionHashes(block *StarknetBlock, chainID ChainID) error {
+	for _, tx := range block.Transactions {
+		if err := tx.VerifyHash(chainID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hashes returns the possible hashes of the transaction: first the current
+// one, and then the deprecated one if the transaction may have been sent
+// before the current one was used.
+func (t TxnSpecificInfo) hashes(chainID *big.Int) []*big.Int {
+	version := feltOf(t.Version)
+	address := feltOf(t.ContractAddress)
+	switch {
+	case t.Type == "DEPLOY":
+		calldata := feltsOf(t.ConstructorCalldata)
+		return []*big.Int{
+			transactionHash(deployHashPrefix, version, address, constructorSelector, calldata, new(big.Int), chainID),
+			deprecatedTransactionHash(deployHashPrefix, address, constructorSelector, calldata, chainID),
+		}
+	case t.Type == "L1_HANDLER" || t.EntryPointType == "L1_HANDLER":
+		selector, calldata := feltOf(t.EntryPointSelector), feltsOf(t.Calldata)
+		if t.Nonce == "" {
+			// The L1 handler transactions without a nonce were hashed as
+			// invoke transactions.
+			return []*big.Int{deprecatedTransactionHash(invokeHashPrefix, address, selector, calldata, chainID)}
+		}
+		return []*big.Int{
+			transactionHash(l1HandlerHashPrefix, version, address, selector, calldata, new(big.Int), chainID, feltOf(t.Nonce)),
+		}
+	default:
+		selector, calldata := feltOf(t.EntryPointSelector), feltsOf(t.Calldata)
+		return []*big.Int{
+			transactionHash(invokeHashPrefix, version, address, selector, calldata, feltOf(t.MaxFee), chainID),
+			deprecatedTransactionHash(invokeHashPrefix, address, selector, calldata, chainID),
+		}
+	}
+}
+
+// transactionHash returns the hash of a transaction:
+//
+//	h(prefix, version, contract_address, selector, h(calldata), max_fee, chain_id, additional...)
+//
+// where h is the Pedersen hash of an array.
+func transactionHash(
+	prefix, version, contractAddress, selector *big.Int, calldata []*big.Int, maxFee, chainID *big.Int,
+	additional ...*big.Int,
+) *big.Int {
+	data := []*big.Int{
+		prefix, version, contractAddress, selector, pedersen.ArrayDigest(calldata...), maxFee, chainID,
+	}
+	return pedersen.ArrayDigest(append(data, additional...)...)
+}
+
+// deprecatedTransactionHash returns the hash of a transaction sent before the
+// version and the max fee were part of the hash:
+//
+//	h(prefix, contract_address, selector, h(calldata), chain_id)
+func deprecatedTransactionHash(prefix, contractAddress, selector *big.Int, calldata []*big.Int, chainID *big.Int) *big.Int {
+	return pedersen.ArrayDigest(prefix, contractAddress, selector, pedersen.ArrayDigest(calldata...), chainID)
+}
+
+// feltOf returns the field element of the given hex string. An empty string
+// is zero.
+func feltOf(s string) *big.Int {
+	return common.HexToFelt(s).Big()
+}
+
+// feltsOf returns the field elements of the given hex strings.
+func feltsOf(s []string) []*big.Int {
+	felts := make([]*big.Int, len(s))
+	for i, v := range s {
+		felts[i] = feltOf(v)
+	}
+	return felts
+}
+
+// toHash returns the hex representation of the given hash.
+func toHash(hash *big.Int) Hash {
+	return Hash("0x" + hash.Text(16))
+}
@@ -2,9 +2,10 @@ package feeder
 
 // notest
 import (
+	"net/url"
+
 	feeder "github.com/NethermindEth/juno/pkg/feeder/abi"
 	"github.com/NethermindEth/juno/pkg/feeder/types"
-	"github.com/NethermindEth/juno/pkg/rpc"
 )
 
 type (
@@ -30,6 +31,40 @@ const (
 	Testnet ChainID = "Goerli"
 )
 
+// LegacyBlocks returns the number of blocks at the start of the chain that
+// were produced before StarkNet 0.7.0, which is block 833 of the mainnet and
+// block 47028 of the testnet. The hashes of these blocks follow a legacy
+// formula, and their deploy transactions may predate the current derivation
+// of the contract addresses. Other chains have no legacy blocks.
+func (c ChainID) LegacyBlocks() uint64 {
+	switch c {
+	case Mainnet:
+		return 833
+	case Testnet:
+		return 47028
+	default:
+		return 0
+	}
+}
+
+// NetworkChainID returns the chain served by the feeder gateway at the given
+// URL, like "https://alpha-mainnet.starknet.io", and true, or false if the
+// gateway is not a known StarkNet gateway.
+func NetworkChainID(gateway string) (ChainID, bool) {
+	u, err := url.Parse(gateway)
+	if err != nil {
+		return "", false
+	}
+	switch u.Hostname() {
+	case "alpha-mainnet.starknet.io":
+		return Mainnet, true
+	case "alpha4.starknet.io":
+		return Testnet, true
+	default:
+		return "", false
+	}
+}
+
 // ContractAddresses represent the response for Starknet contract
 // address details.
 type ContractAddresses struct {
@@ -62,15 +97,19 @@ type Transaction interface {
 
 // InvokeFunction represents a transaction in the StarkNet network that
 // is an invocation of a Cairo contract function.
+// All the fields are hex-encoded field elements.
 type InvokeFunction struct {
-	ContractAddress int `json:"contract_address"`
+	ContractAddress string `json:"contract_address"`
 	// A field element that encodes the signature of the called function.
-	EntryPointSelector int   `json:"entry_point_selector"`
-	Calldata           []int `json:"calldata"`
+	EntryPointSelector string   `json:"entry_point_selector"`
+	Calldata           []string `json:"calldata"`
 	// Additional information given by the caller that represents the
 	// signature of the transaction. The exact way this field is handled
 	// is defined by the called contract's function, like calldata.
-	Signature []int `json:"signature"`
+	Signature []string `json:"signature"`
+	// The maximum fee that the sender is willing to pay for the
+	// transaction.
+	MaxFee string `json:"max_fee,omitempty"`
 }
 
 // TransactionType returns the TxnType related to InvokeFunction
@@ -78,23 +117,21 @@ func (i InvokeFunction) TransactionType() TxnType {
 	return Invoke
 }
 
-// XXX: Document.
-// CalculateHash returns the hash of the tra
-func (i InvokeFunction) CalculateHash(config StarknetGeneralConfig) Hash {
-	// TODO: implement this
-	return Hash(config.ChainID)
-}
-
 // TxnSpecificInfo represent a StarkNet transaction information.
 type TxnSpecificInfo struct {
-	Calldata            []string `json:"constructor_calldata"`
+	Calldata            []string `json:"calldata"`
+	ConstructorCalldata []string `json:"constructor_calldata"`
 	ContractAddress     string   `json:"contract_address"`
 	ContractAddressSalt string   `json:"contract_address_salt"`
 	EntryPointSelector  string   `json:"entry_point_selector"`
 	EntryPointType      string   `json:"entry_point_type"`
-	Signature           []string `json:"signature"`
-	TransactionHash     string   `json:"transaction_hash"`
-	Type                string   `json:"type"`
+	MaxFee              string   `json:"max_fee"`
+	// Nonce is the nonce of the L1 message of an L1 handler transaction.
+	Nonce           string   `json:"nonce,omitempty"`
+	Signature       []string `json:"signature"`
+	TransactionHash string   `json:"transaction_hash"`
+	Type            string   `json:"type"`
+	Version         string   `json:"version,omitempty"`
 }
 
 // L1ToL2Message Represents a StarkNet L1-to-L2 message.
@@ -155,7 +192,7 @@ type StarknetBlock struct {
 	GasPrice            string                 `json:"gas_price"`
 	SequencerAddress    string                 `json:"sequencer_address"`
 	StateRoot           string                 `json:"state_root"`
-	Status              rpc.BlockStatus        `json:"status"`
+	Status              string                 `json:"status"`
 	Transactions        []TxnSpecificInfo      `json:"transactions"`
 	Timestamp           int64                  `json:"timestamp"`
 	TransactionReceipts []TransactionExecution `json:"transaction_receipts"`
@@ -218,6 +255,19 @@ type TransactionReceipt struct {
 	TransactionInBlockInfo
 }
 
+// AddTransactionResponse represents the response of the gateway to a
+// transaction submission.
+type AddTransactionResponse struct {
+	// Code is the status of the submission, "TRANSACTION_RECEIVED" if the
+	// transaction was accepted by the gateway.
+	Code string `json:"code"`
+	// TransactionHash is the hash assigned to the transaction.
+	TransactionHash string `json:"transaction_hash"`
+	// Address is the address of the deployed contract. It's only set for
+	// deploy transactions.
+	Address string `json:"address,omitempty"`
+}
+
 // KV represents a key-value pair.
 type KV struct {
 	Key   string `json:"key"`
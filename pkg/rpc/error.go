@@ -14,6 +14,8 @@ const (
 	ErrorCodeInvalidParams ErrorCode = -32_602
 	// ErrorCodeInternal is internal error code.
 	ErrorCodeInternal ErrorCode = -32_603
+	// ErrorCodeBlockNotFound is the StarkNet block not found error code.
+	ErrorCodeBlockNotFound ErrorCode = 24
 )
 
 type (
@@ -58,3 +60,8 @@ func ErrInvalidParams() *Error {
 func ErrInternal() *Error {
 	return &Error{Code: ErrorCodeInternal, Message: "Internal error."}
 }
+
+// ErrBlockNotFound returns block not found error.
+func ErrBlockNotFound() *Error {
+	return &Error{Code: ErrorCodeBlockNotFound, Message: "Block not found"}
+}
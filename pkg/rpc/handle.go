@@ -353,12 +353,18 @@ func (h *HandlerJsonRpc) InvokeMethod(
 	resFromCall, err := callFunc(
 		c, r.Method, args, fn.Func, structToCall, hasContext, errPos)
 	if err != nil {
-		// notest
-		log.Default.With(
-			"Method", r.Method, "Params", r.Params,
-		).Error("Internal error occurred while calling the function.")
-		res.Error = ErrInternal()
+		// The JSON-RPC errors returned by the method are sent as they are.
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			// notest
+			log.Default.With(
+				"Method", r.Method, "Params", r.Params,
+			).Error("Internal error occurred while calling the function.")
+			rpcErr = ErrInternal()
+		}
+		res.Error = rpcErr
 		res.Result = nil
+		return res
 	}
 	log.Default.With("Method", r.Method).Info("Request successful.")
 	res.Result = resFromCall
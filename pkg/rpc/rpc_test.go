@@ -6,11 +6,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/NethermindEth/juno/pkg/feeder"
 )
 
 func getServerHandler() *HandlerJsonRpc {
@@ -70,7 +74,7 @@ func TestRPCServer(t *testing.T) {
 }
 
 func TestServer(t *testing.T) {
-	server := NewServer(":8080")
+	server := NewServer(":8080", nil, nil)
 	go func() {
 		_ = server.ListenAndServe()
 	}()
@@ -78,3 +82,119 @@ func TestServer(t *testing.T) {
 	server.Close(ctx)
 	cancel()
 }
+
+func TestFeederTxnsToTxns(t *testing.T) {
+	txs := []feeder.TxnSpecificInfo{
+		{
+			ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+			EntryPointSelector: "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+			Calldata:           []string{"0x5", "0x22b"},
+			TransactionHash:    "0x12c96ae3c050771689eb261c9bf78fac2580708c7f1f3d69a9647d8be59f1e1",
+		},
+	}
+	want := []Txn{
+		{
+			FunctionCall: FunctionCall{
+				ContractAddress:    "0x20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6",
+				EntryPointSelector: "0x317eb442b72a9fae758d4fb26830ed0d9f31c8e7da4dbff4e8c59ea6a158e7f",
+				CallData:           []string{"0x5", "0x22b"},
+			},
+			TxnHash: "0x12c96ae3c050771689eb261c9bf78fac2580708c7f1f3d69a9647d8be59f1e1",
+		},
+	}
+	if got := feederTxnsToTxns(txs); !reflect.DeepEqual(got, want) {
+		t.Errorf("feederTxnsToTxns() = %+v, want %+v", got, want)
+	}
+}
+
+// pendingBlockFunc is a PendingBlockProvider that calls itself.
+type pendingBlockFunc func() *feeder.StarknetBlock
+
+func (f pendingBlockFunc) PendingBlock() *feeder.StarknetBlock {
+	return f()
+}
+
+func TestPendingBlock(t *testing.T) {
+	var pending *feeder.StarknetBlock
+	handler := HandlerRPC{pending: pendingBlockFunc(func() *feeder.StarknetBlock { return pending })}
+	ctx := context.Background()
+
+	if _, err := handler.StarknetGetBlockByNumber(ctx, string(PendingTag)); !reflect.DeepEqual(err, ErrBlockNotFound()) {
+		t.Errorf("unexpected error %v without a pending block, want %v", err, ErrBlockNotFound())
+	}
+	if _, err := handler.StarknetGetBlockTransactionCountByHash(ctx, BlockHashOrTag(PendingTag)); !reflect.DeepEqual(err, ErrBlockNotFound()) {
+		t.Errorf("unexpected error %v without a pending block, want %v", err, ErrBlockNotFound())
+	}
+	if txs, err := handler.StarknetPendingTransactions(ctx); err != nil || txs != nil {
+		t.Errorf("unexpected pending transactions %v, %v without a pending block", txs, err)
+	}
+
+	pending = &feeder.StarknetBlock{
+		ParentBlockHash: "0x2a70fb03fe363a2d6be843343a1d81ce6abeda1e9bd5cc6ad8fa9f45e30fdeb",
+		Transactions:    []feeder.TxnSpecificInfo{{TransactionHash: "0x1"}},
+	}
+	b, err := handler.StarknetGetBlockByHash(ctx, BlockHashOrTag(PendingTag))
+	if err != nil {
+		t.Fatalf("unexpected error getting the pending block: %s", err)
+	}
+	if b.Status != Pending || b.ParentHash != pending.ParentBlockHash || len(b.Transactions) != 1 {
+		t.Errorf("unexpected pending block %+v", b)
+	}
+	count, err := handler.StarknetGetBlockTransactionCountByNumber(ctx, string(PendingTag))
+	if err != nil || count.TransactionCount != 1 {
+		t.Errorf("unexpected transaction count %v, %v of the pending block, want 1", count, err)
+	}
+}
+
+// storageFunc is a StorageProvider that calls itself.
+type storageFunc func(address, key, blockHash *big.Int) (*big.Int, bool)
+
+func (f storageFunc) StorageAt(address, key, blockHash *big.Int) (*big.Int, bool) {
+	return f(address, key, blockHash)
+}
+
+func TestStorageAt(t *testing.T) {
+	// The storage has the value 0x1f at key 0x2 of contract 0x1 at the block
+	// 0xb0 and 0x20 at the latest block.
+	storage := storageFunc(func(address, key, blockHash *big.Int) (*big.Int, bool) {
+		if address.Cmp(big.NewInt(1)) != 0 || key.Cmp(big.NewInt(2)) != 0 {
+			return new(big.Int), true
+		}
+		switch {
+		case blockHash == nil:
+			return big.NewInt(0x20), true
+		case blockHash.Cmp(big.NewInt(0xb0)) == 0:
+			return big.NewInt(0x1f), true
+		default:
+			return nil, false
+		}
+	})
+	handler := HandlerRPC{storage: storage}
+	ctx := context.Background()
+
+	tests := [...]struct {
+		address Address
+		key     Felt
+		block   BlockHashOrTag
+		want    Felt
+	}{
+		{"0x1", "0x2", BlockHashOrTag(LatestTag), "0x20"},
+		{"0x1", "0x2", BlockHashOrTag(PendingTag), "0x20"},
+		{"0x1", "0x2", "0xb0", "0x1f"},
+		{"0x01", "0x002", "0x00b0", "0x1f"},
+		{"0x1", "0x3", "0xb0", "0x0"},
+	}
+	for _, test := range tests {
+		value, err := handler.StarknetGetStorageAt(ctx, test.address, test.key, test.block)
+		if err != nil || value != test.want {
+			t.Errorf("storage %s at key %s of block %s: got %v, %v, want %s",
+				test.address, test.key, test.block, value, err, test.want)
+		}
+	}
+	if _, err := handler.StarknetGetStorageAt(ctx, "0x1", "0x2", "0xb1"); !reflect.DeepEqual(err, ErrBlockNotFound()) {
+		t.Errorf("unexpected error %v for an unknown block, want %v", err, ErrBlockNotFound())
+	}
+	if _, err := (HandlerRPC{}).StarknetGetStorageAt(ctx, "0x1", "0x2", BlockHashOrTag(LatestTag)); !reflect.DeepEqual(err, ErrBlockNotFound()) {
+		t.Errorf("unexpected error %v without a state, want %v", err, ErrBlockNotFound())
+	}
+}
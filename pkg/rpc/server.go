@@ -2,9 +2,12 @@ package rpc
 
 import (
 	"context"
+	"math/big"
 	"net/http"
 
 	"github.com/NethermindEth/juno/internal/log"
+	"github.com/NethermindEth/juno/pkg/common"
+	"github.com/NethermindEth/juno/pkg/feeder"
 )
 
 // Server represents the server structure
@@ -12,9 +15,31 @@ type Server struct {
 	server http.Server
 }
 
+// PendingBlockProvider provides the pending block served for the
+// "pending" block tag.
+type PendingBlockProvider interface {
+	// PendingBlock returns the pending block, or nil if there is none.
+	PendingBlock() *feeder.StarknetBlock
+}
+
+// StorageProvider provides the storage of the contracts at the synced
+// blocks.
+type StorageProvider interface {
+	// StorageAt returns the value of the given storage key of the contract
+	// at the given address as it was at the block with the given hash, or
+	// at the latest block if blockHash is nil, and false if the state of
+	// the block is not available.
+	StorageAt(address, key, blockHash *big.Int) (*big.Int, bool)
+}
+
 // HandlerRPC represents the struct that later we will apply reflection
 // to call rpc methods.
-type HandlerRPC struct{}
+type HandlerRPC struct {
+	// pending provides the pending block, if not nil.
+	pending PendingBlockProvider
+	// storage provides the storage of the contracts, if not nil.
+	storage StorageProvider
+}
 
 // HandlerJsonRpc contains the JSON-RPC method functions.
 type HandlerJsonRpc struct {
@@ -26,10 +51,12 @@ func NewHandlerJsonRpc(rpc interface{}) *HandlerJsonRpc {
 	return &HandlerJsonRpc{StructRpc: rpc}
 }
 
-// NewServer creates a new server.
-func NewServer(addr string) *Server {
+// NewServer creates a new server that serves the pending block provided
+// by pending, which may be nil if there is no pending block, and the
+// storage provided by storage, which may be nil if there is no state.
+func NewServer(addr string, pending PendingBlockProvider, storage StorageProvider) *Server {
 	mux := http.NewServeMux()
-	mux.Handle("/rpc", NewHandlerJsonRpc(HandlerRPC{}))
+	mux.Handle("/rpc", NewHandlerJsonRpc(HandlerRPC{pending: pending, storage: storage}))
 	return &Server{server: http.Server{Addr: addr, Handler: mux}}
 }
 
@@ -75,18 +102,24 @@ func (HandlerRPC) StarknetCall(
 
 // StarknetGetBlockByHash represent the handler for getting a block by
 // its hash.
-func (HandlerRPC) StarknetGetBlockByHash(
+func (h HandlerRPC) StarknetGetBlockByHash(
 	c context.Context, blockHash BlockHashOrTag,
 ) (BlockResponse, error) {
+	if BlockTag(blockHash) == PendingTag {
+		return h.pendingBlockResponse()
+	}
 	// TODO See if is possible to support overhead without another method
 	return BlockResponse{BlockHash: string(blockHash)}, nil
 }
 
 // StarknetGetBlockByHashOpt represent the handler for getting a block
 // by its hash.
-func (HandlerRPC) StarknetGetBlockByHashOpt(
+func (h HandlerRPC) StarknetGetBlockByHashOpt(
 	c context.Context, blockHash BlockHashOrTag, requestedScope RequestedScope,
 ) (BlockResponse, error) {
+	if BlockTag(blockHash) == PendingTag {
+		return h.pendingBlockResponse()
+	}
 	// TODO See if is possible to support overhead without another method
 	return BlockResponse{
 		BlockHash:  string(blockHash),
@@ -98,36 +131,48 @@ func (HandlerRPC) StarknetGetBlockByHashOpt(
 
 // StarknetGetBlockByNumber represent the handler for getting a block by
 // its number.
-func (HandlerRPC) StarknetGetBlockByNumber(
+func (h HandlerRPC) StarknetGetBlockByNumber(
 	c context.Context, blockNumber interface{},
 ) (BlockResponse, error) {
 	// TODO See if is possible to support overhead without another method
 	log.Default.With("Block Number", blockNumber).Info("Calling StarknetGetBlockByNumber")
+	if isPendingTag(blockNumber) {
+		return h.pendingBlockResponse()
+	}
 	return BlockResponse{}, nil
 }
 
 // StarknetGetBlockByNumberOpt represent the handler for getting a block
 // by its number.
-func (HandlerRPC) StarknetGetBlockByNumberOpt(
+func (h HandlerRPC) StarknetGetBlockByNumberOpt(
 	c context.Context, blockNumber interface{}, requestedScope RequestedScope,
 ) (BlockResponse, error) {
+	if isPendingTag(blockNumber) {
+		return h.pendingBlockResponse()
+	}
 	// TODO See if is possible to support overhead without another method
 	return BlockResponse{}, nil
 }
 
 // StarknetGetBlockTransactionCountByHash represent the handler for
 // getting block transaction count by the blocks hash.
-func (HandlerRPC) StarknetGetBlockTransactionCountByHash(
+func (h HandlerRPC) StarknetGetBlockTransactionCountByHash(
 	c context.Context, blockHash BlockHashOrTag,
 ) (BlockTransactionCount, error) {
+	if BlockTag(blockHash) == PendingTag {
+		return h.pendingBlockTransactionCount()
+	}
 	return BlockTransactionCount{}, nil
 }
 
 // StarknetGetBlockTransactionCountByNumber Get the number of
 // transactions in a block given a block number (height).
-func (HandlerRPC) StarknetGetBlockTransactionCountByNumber(
+func (h HandlerRPC) StarknetGetBlockTransactionCountByNumber(
 	c context.Context, blockNumber interface{},
 ) (BlockTransactionCount, error) {
+	if isPendingTag(blockNumber) {
+		return h.pendingBlockTransactionCount()
+	}
 	return BlockTransactionCount{}, nil
 }
 
@@ -141,13 +186,27 @@ func (HandlerRPC) StarknetGetStateUpdateByHash(
 
 // StarknetGetStorageAt Get the value of the storage at the given
 // address and key.
-func (HandlerRPC) StarknetGetStorageAt(
+func (h HandlerRPC) StarknetGetStorageAt(
 	c context.Context,
 	contractAddress Address,
 	key Felt,
 	blockHash BlockHashOrTag,
 ) (Felt, error) {
-	return "Storage", nil
+	if h.storage == nil {
+		return "", ErrBlockNotFound()
+	}
+	// The state diff of the pending block is not kept, so the storage of
+	// the latest block, which the pending block is built on, is served.
+	var hash *big.Int
+	if tag := BlockTag(blockHash); tag != LatestTag && tag != PendingTag {
+		hash = common.HexToFelt(string(blockHash)).Big()
+	}
+	value, ok := h.storage.StorageAt(
+		common.HexToFelt(string(contractAddress)).Big(), common.HexToFelt(string(key)).Big(), hash)
+	if !ok {
+		return "", ErrBlockNotFound()
+	}
+	return Felt("0x" + value.Text(16)), nil
 }
 
 // StarknetGetTransactionByHash Get the details and status of a
@@ -203,10 +262,15 @@ func (HandlerRPC) StarknetChainId(c context.Context) (ChainID, error) {
 
 // StarknetPendingTransactions Returns the transactions in the
 // transaction pool, recognized by this sequencer.
-func (HandlerRPC) StarknetPendingTransactions(
+func (h HandlerRPC) StarknetPendingTransactions(
 	c context.Context,
 ) ([]Txn, error) {
-	return nil, nil
+	b, err := h.pendingBlock()
+	if err != nil {
+		// There are no pending transactions without a pending block.
+		return nil, nil
+	}
+	return feederTxnsToTxns(b.Transactions), nil
 }
 
 // StarknetProtocolVersion Returns the current starknet protocol version
@@ -232,3 +296,65 @@ func (HandlerRPC) StarknetGetEvents(
 ) (EventResponse, error) {
 	return EventResponse{}, nil
 }
+
+// isPendingTag returns true if the given block number param is the pending
+// block tag.
+func isPendingTag(blockNumber interface{}) bool {
+	tag, ok := blockNumber.(string)
+	return ok && BlockTag(tag) == PendingTag
+}
+
+// pendingBlock returns the pending block, or a block not found error if
+// there is none.
+func (h HandlerRPC) pendingBlock() (*feeder.StarknetBlock, error) {
+	if h.pending != nil {
+		if b := h.pending.PendingBlock(); b != nil {
+			return b, nil
+		}
+	}
+	return nil, ErrBlockNotFound()
+}
+
+// pendingBlockResponse builds the BlockResponse of the pending block.
+func (h HandlerRPC) pendingBlockResponse() (BlockResponse, error) {
+	b, err := h.pendingBlock()
+	if err != nil {
+		return BlockResponse{}, err
+	}
+	return BlockResponse{
+		ParentHash:   b.ParentBlockHash,
+		Status:       Pending,
+		Sequencer:    b.SequencerAddress,
+		Transactions: feederTxnsToTxns(b.Transactions),
+	}, nil
+}
+
+// pendingBlockTransactionCount returns the number of transactions of the
+// pending block.
+func (h HandlerRPC) pendingBlockTransactionCount() (BlockTransactionCount, error) {
+	b, err := h.pendingBlock()
+	if err != nil {
+		return BlockTransactionCount{}, err
+	}
+	return BlockTransactionCount{TransactionCount: len(b.Transactions)}, nil
+}
+
+// feederTxnsToTxns converts the transactions returned by the feeder gateway
+// to the RPC representation.
+func feederTxnsToTxns(txs []feeder.TxnSpecificInfo) []Txn {
+	if txs == nil {
+		return nil
+	}
+	out := make([]Txn, len(txs))
+	for i, tx := range txs {
+		out[i] = Txn{
+			FunctionCall: FunctionCall{
+				ContractAddress:    tx.ContractAddress,
+				EntryPointSelector: tx.EntryPointSelector,
+				CallData:           tx.Calldata,
+			},
+			TxnHash: TxnHash(tx.TransactionHash),
+		}
+	}
+	return out
+}
@@ -24,6 +24,11 @@ const (
 	Rejected     BlockStatus = "REJECTED"
 )
 
+const (
+	LatestTag  BlockTag = "latest"
+	PendingTag BlockTag = "pending"
+)
+
 var (
 	FailedToReceiveTxn     = ResponseError{1, "Failed to write transaction"}
 	ContractNotFound       = ResponseError{20, "Contract not found"}
@@ -235,7 +240,7 @@ type BlockResponse struct {
 	// When the block was accepted on L1. Formatted as...
 	AcceptedTime uint64 `json:"accepted_time"`
 	// Transactions in the Block
-	Transactions []Transactions `json:"transactions"`
+	Transactions []Txn `json:"transactions"`
 }
 
 type RequestedScope string
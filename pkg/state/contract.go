@@ -0,0 +1,60 @@
+package state
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// feltLen is the length in bytes of the field elements of a contract
+// state when encoded.
+const feltLen = 32
+
+// errInvalidContractState is returned when decoding a malformed contract
+// state.
+var errInvalidContractState = errors.New("invalid contract state encoding")
+
+// ContractState is the state of a contract, whose hash is the leaf of
+// the contract in the global state trie.
+type ContractState struct {
+	// ClassHash is the hash of the class of the contract.
+	ClassHash *big.Int
+	// StorageRoot is the root of the storage trie of the contract.
+	StorageRoot *big.Int
+	// Nonce is the nonce of the contract.
+	Nonce *big.Int
+}
+
+// Hash returns the hash of the contract state, H(H(H(class hash, storage
+// root), nonce), 0), where the last element is the version of the
+// contract state hash.
+func (c *ContractState) Hash() *big.Int {
+	h := pedersen.Digest(c.ClassHash, c.StorageRoot)
+	h = pedersen.Digest(h, c.Nonce)
+	return pedersen.Digest(h, new(big.Int))
+}
+
+// bytes returns the binary representation of the contract state: the
+// class hash, the storage root and the nonce, each one as a 32-byte
+// big-endian field element.
+func (c *ContractState) bytes() []byte {
+	b := make([]byte, 3*feltLen)
+	c.ClassHash.FillBytes(b[:feltLen])
+	c.StorageRoot.FillBytes(b[feltLen : 2*feltLen])
+	c.Nonce.FillBytes(b[2*feltLen:])
+	return b
+}
+
+// decodeContractState returns the contract state with the given binary
+// representation, as returned by ContractState.bytes.
+func decodeContractState(b []byte) (*ContractState, error) {
+	if len(b) != 3*feltLen {
+		return nil, errInvalidContractState
+	}
+	return &ContractState{
+		ClassHash:   new(big.Int).SetBytes(b[:feltLen]),
+		StorageRoot: new(big.Int).SetBytes(b[feltLen : 2*feltLen]),
+		Nonce:       new(big.Int).SetBytes(b[2*feltLen:]),
+	}, nil
+}
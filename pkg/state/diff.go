@@ -0,0 +1,43 @@
+package state
+
+import (
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/trie"
+)
+
+// ContractDiff is a contract that differs between two states.
+type ContractDiff struct {
+	Address *big.Int
+	// Old and New are the states of the contract in each state, nil if
+	// the contract doesn't exist or its state is unknown.
+	Old *ContractState
+	New *ContractState
+	// Storage are the storage keys of the contract that differ.
+	Storage []trie.Change
+}
+
+// Diff returns the contracts that differ from the state from to the
+// state to, in increasing address order, with the storage keys that
+// differ. The states may be versions of the same state, as returned by
+// At, or states on different stores. The pending changes are not
+// included. Only the parts of the tries that differ are read, as
+// described in trie.Diff.
+func Diff(from, to *State) []ContractDiff {
+	changes := trie.Diff(&from.global, &to.global)
+	diffs := make([]ContractDiff, 0, len(changes))
+	for _, change := range changes {
+		diff := ContractDiff{Address: change.Key}
+		if change.Old != nil {
+			diff.Old, _ = from.contractState(change.Old)
+		}
+		if change.New != nil {
+			diff.New, _ = to.contractState(change.New)
+		}
+		fromStorage := from.committedStorageTrie(change.Key)
+		toStorage := to.committedStorageTrie(change.Key)
+		diff.Storage = trie.Diff(&fromStorage, &toStorage)
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+func TestDiff(t *testing.T) {
+	s := New(store.New())
+	genesisState(t, s)
+	genesis, _ := s.At(0)
+
+	t.Run("genesis", func(t *testing.T) {
+		// The diff from the empty state is the state diff of the block.
+		diffs := Diff(New(store.New()), genesis)
+		if len(diffs) != len(genesisStorage) {
+			t.Fatalf("diff has %d contracts, want %d", len(diffs), len(genesisStorage))
+		}
+		for _, diff := range diffs {
+			storage, ok := genesisStorage[diff.Address.Text(16)]
+			if !ok {
+				t.Errorf("unexpected contract %x", diff.Address)
+				continue
+			}
+			if diff.Old != nil || diff.New == nil || diff.New.ClassHash.Cmp(genesisClassHash) != 0 {
+				t.Errorf("contract %x: unexpected states from %v to %v", diff.Address, diff.Old, diff.New)
+			}
+			if len(diff.Storage) != len(storage) {
+				t.Errorf("contract %x: %d storage changes, want %d", diff.Address, len(diff.Storage), len(storage))
+			}
+			for _, change := range diff.Storage {
+				want, ok := storage[change.Key.Text(16)]
+				if !ok || change.Old != nil || change.New.Cmp(hexInt(want)) != 0 {
+					t.Errorf("contract %x: unexpected change %v", diff.Address, change)
+				}
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		address := hexInt("20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6")
+		removed := hexInt("5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5")
+		deployed := hexInt("dead")
+		s.SetStorage(address, big.NewInt(5), big.NewInt(0x22c))
+		s.SetStorage(address, removed, new(big.Int))
+		s.SetClassHash(deployed, big.NewInt(1))
+		s.SetStorage(deployed, big.NewInt(1), big.NewInt(1))
+		if _, err := s.Commit(1); err != nil {
+			t.Fatalf("unexpected error committing the version 1: %s", err)
+		}
+
+		diffs := Diff(genesis, s)
+		if len(diffs) != 2 || diffs[0].Address.Cmp(deployed) != 0 || diffs[1].Address.Cmp(address) != 0 {
+			t.Fatalf("unexpected diff %v, want the contracts %x and %x", diffs, deployed, address)
+		}
+		if d := diffs[0]; d.Old != nil || d.New == nil || len(d.Storage) != 1 || d.Storage[0].New.Int64() != 1 {
+			t.Errorf("unexpected diff %v of the deployed contract", d)
+		}
+		d := diffs[1]
+		if d.Old == nil || d.New == nil || d.Old.StorageRoot.Cmp(d.New.StorageRoot) == 0 {
+			t.Errorf("unexpected states from %v to %v of the updated contract", d.Old, d.New)
+		}
+		if len(d.Storage) != 2 {
+			t.Fatalf("unexpected storage changes %v of the updated contract", d.Storage)
+		}
+		if c := d.Storage[0]; c.Key.Int64() != 5 || c.Old.Int64() != 0x22b || c.New.Int64() != 0x22c {
+			t.Errorf("unexpected change %v, want 5 from 22b to 22c", c)
+		}
+		if c := d.Storage[1]; c.Key.Cmp(removed) != 0 || c.Old.Int64() != 0x7e5 || c.New != nil {
+			t.Errorf("unexpected change %v, want %x removed", c, removed)
+		}
+
+		if diffs := Diff(s, s); len(diffs) != 0 {
+			t.Errorf("unexpected diff %v between a state and itself", diffs)
+		}
+	})
+}
@@ -0,0 +1,402 @@
+// Package state implements the StarkNet state on top of [trie.Trie] and
+// a [store.Storer].
+//
+// Each contract has its own storage trie, mapping the storage keys of
+// the contract to their values, and the global state trie maps the
+// address of each contract to the hash of its [ContractState]: its
+// class hash, the root of its storage trie and its nonce. The root of
+// the global state trie is the state commitment of a block.
+//
+// # Versions
+//
+// The changes to the state are kept in memory until they are applied
+// with [State.Commit], which records the resulting state as a version,
+// usually a block number. [State.At] returns the state as it was at a
+// version, and [State.Revert] undoes the latest version. Like the tries
+// it's built on, a state created with [NewWithPruning] keeps the given
+// number of latest versions only.
+//
+// # Layout
+//
+// The store holds the global state trie under the "state:" prefix and
+// the storage trie of each contract under the "storage:" prefix followed
+// by the address of the contract in hex and ":". The contract states
+// are keyed by their hash, under the "contract:" prefix, so the contract
+// state of any leaf of any version of the global state trie can be
+// found. Each one counts the times it was committed, and is deleted once
+// the versions of all those commits are pruned or reverted. The
+// addresses of the contracts changed by each version are kept under the
+// "diff:" prefix, to revert it and to find the contract states that only
+// the pruned versions have.
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/NethermindEth/juno/pkg/store"
+	"github.com/NethermindEth/juno/pkg/trie"
+)
+
+// height is the height of the global state trie and of the contract
+// storage tries.
+const height = 251
+
+// State represents the StarkNet state.
+type State struct {
+	store store.Storer
+	// keep is the number of versions to keep, or zero or less to keep them
+	// all.
+	keep   int
+	global trie.Trie
+	// readOnly tells whether the state is a past version returned by At.
+	readOnly bool
+	// pending are the changes to the contracts not committed yet, by
+	// address.
+	pending map[string]*pendingContract
+}
+
+// pendingContract holds the uncommitted changes to a contract. A nil
+// field is left unchanged.
+type pendingContract struct {
+	address     *big.Int
+	classHash   *big.Int
+	nonce       *big.Int
+	storageTrie trie.Trie
+	storage     *trie.Batch
+}
+
+// New constructs a new state that keeps all its versions.
+func New(store store.Storer) *State {
+	return NewWithPruning(store, 0)
+}
+
+// NewWithPruning constructs a new state that keeps the given number of
+// latest versions, or all of them if keep is zero or less.
+func NewWithPruning(store store.Storer, keep int) *State {
+	return &State{
+		store:   store,
+		keep:    keep,
+		global:  trie.NewWithPruning(prefixStore{store, []byte("state:")}, height, keep),
+		pending: make(map[string]*pendingContract),
+	}
+}
+
+// Root returns the root of the global state trie, which is zero for an
+// empty state. The changes not committed yet are not included.
+func (s *State) Root() *big.Int {
+	return s.global.Commitment()
+}
+
+// ContractState returns the state of the contract with the given
+// address, and false if there is no such contract.
+func (s *State) ContractState(address *big.Int) (*ContractState, bool) {
+	hash, ok := s.global.Get(address)
+	if !ok {
+		return nil, false
+	}
+	return s.contractState(hash)
+}
+
+// contractState returns the contract state with the given hash, and
+// false if it's unknown.
+func (s *State) contractState(hash *big.Int) (*ContractState, bool) {
+	contract, _, ok := s.loadContractState(hash)
+	return contract, ok
+}
+
+// loadContractState returns the contract state with the given hash and
+// the number of times it was committed, and false if it's unknown.
+func (s *State) loadContractState(hash *big.Int) (*ContractState, uint32, bool) {
+	b, ok := s.store.Get(contractKey(hash))
+	if !ok {
+		// The contract state is unknown, which happens only to the
+		// contracts migrated from stores that didn't keep it.
+		return nil, 0, false
+	}
+	if len(b) != 3*feltLen+4 {
+		// notest
+		panic(any(fmt.Errorf("contract state %x: %w", hash, errInvalidContractState)))
+	}
+	contract, err := decodeContractState(b[:3*feltLen])
+	if err != nil {
+		// notest
+		panic(any(fmt.Errorf("contract state %x: %w", hash, err)))
+	}
+	return contract, binary.BigEndian.Uint32(b[3*feltLen:]), true
+}
+
+// storeContractState stores the given contract state with the given
+// number of times it was committed, or deletes it if there are none.
+func (s *State) storeContractState(hash *big.Int, contract *ContractState, refs uint32) {
+	if refs == 0 {
+		s.store.Delete(contractKey(hash))
+		return
+	}
+	b := append(contract.bytes(), make([]byte, 4)...)
+	binary.BigEndian.PutUint32(b[3*feltLen:], refs)
+	s.store.Put(contractKey(hash), b)
+}
+
+// releaseContractState undoes one of the commits of the contract state
+// with the given hash, deleting it after the last one.
+func (s *State) releaseContractState(hash *big.Int) {
+	contract, refs, ok := s.loadContractState(hash)
+	if ok {
+		s.storeContractState(hash, contract, refs-1)
+	}
+}
+
+// GetStorage returns the value of the given storage key of the contract
+// with the given address, which is zero if it isn't set.
+func (s *State) GetStorage(address, key *big.Int) *big.Int {
+	storageTrie := s.committedStorageTrie(address)
+	value, ok := storageTrie.Get(key)
+	if !ok {
+		return new(big.Int)
+	}
+	return value
+}
+
+// StorageIterator returns an iterator over the storage keys of the
+// contract with the given address that are set, in increasing order,
+// from start, included, to end, excluded. A nil start begins at the
+// first key and a nil end runs to the last one.
+func (s *State) StorageIterator(address, start, end *big.Int) *trie.Iterator {
+	storageTrie := s.committedStorageTrie(address)
+	return storageTrie.NewIterator(start, end)
+}
+
+// committedStorageTrie returns the storage trie of the contract with the
+// given address as it is in the state, without the pending changes.
+func (s *State) committedStorageTrie(address *big.Int) trie.Trie {
+	storageTrie := s.storageTrie(address)
+	if !s.readOnly {
+		return storageTrie
+	}
+	root := new(big.Int)
+	if contract, ok := s.ContractState(address); ok {
+		root = contract.StorageRoot
+	}
+	return storageTrie.AtRoot(root)
+}
+
+// SetClassHash sets the class hash of the contract with the given
+// address, deploying it if it doesn't exist.
+func (s *State) SetClassHash(address, classHash *big.Int) {
+	s.contract(address).classHash = new(big.Int).Set(classHash)
+}
+
+// SetNonce sets the nonce of the contract with the given address.
+func (s *State) SetNonce(address, nonce *big.Int) {
+	s.contract(address).nonce = new(big.Int).Set(nonce)
+}
+
+// SetStorage sets the value of the given storage key of the contract
+// with the given address. A zero value deletes the key.
+func (s *State) SetStorage(address, key, value *big.Int) {
+	contract := s.contract(address)
+	if contract.storage == nil {
+		contract.storage = contract.storageTrie.NewBatch()
+	}
+	contract.storage.Put(key, value)
+}
+
+// contract returns the pending changes to the contract with the given
+// address.
+func (s *State) contract(address *big.Int) *pendingContract {
+	if s.readOnly {
+		panic(any("state: update of a past version"))
+	}
+	contract, ok := s.pending[address.Text(16)]
+	if !ok {
+		contract = &pendingContract{
+			address:     new(big.Int).Set(address),
+			storageTrie: s.storageTrie(address),
+		}
+		s.pending[address.Text(16)] = contract
+	}
+	return contract
+}
+
+// Commit applies the pending changes to the tries and records the
+// resulting state as the given version, which must be greater than the
+// versions recorded before. It returns the new root of the global state
+// trie. If a changed contract has no class hash, an error is returned
+// and the pending changes are discarded without applying any of them.
+func (s *State) Commit(version uint64) (*big.Int, error) {
+	contracts := make([]*pendingContract, 0, len(s.pending))
+	states := make(map[*pendingContract]*ContractState, len(s.pending))
+	for _, contract := range s.pending {
+		state, ok := s.ContractState(contract.address)
+		if !ok {
+			state = &ContractState{Nonce: new(big.Int)}
+		}
+		if contract.classHash != nil {
+			state.ClassHash = contract.classHash
+		}
+		if state.ClassHash == nil {
+			s.pending = make(map[string]*pendingContract)
+			return nil, fmt.Errorf("contract %x has no class hash", contract.address)
+		}
+		if contract.nonce != nil {
+			state.Nonce = contract.nonce
+		}
+		contracts = append(contracts, contract)
+		states[contract] = state
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].address.Cmp(contracts[j].address) < 0
+	})
+
+	batch := s.global.NewBatch()
+	diff := make([]byte, 0, len(contracts)*feltLen)
+	for _, contract := range contracts {
+		if contract.storage != nil {
+			contract.storage.Commit()
+			contract.storageTrie.Snapshot(version)
+		}
+		state := states[contract]
+		state.StorageRoot = contract.storageTrie.Commitment()
+		hash := state.Hash()
+		_, refs, _ := s.loadContractState(hash)
+		s.storeContractState(hash, state, refs+1)
+		batch.Put(contract.address, hash)
+		diff = append(diff, contract.address.FillBytes(make([]byte, feltLen))...)
+	}
+	batch.Commit()
+	s.store.Put(diffKey(version), diff)
+
+	var pruned []*big.Int
+	if s.keep > 0 && version >= uint64(s.keep) {
+		pruned = s.replacedContractStates(version - uint64(s.keep))
+	}
+	s.global.Snapshot(version)
+	for _, hash := range pruned {
+		s.releaseContractState(hash)
+	}
+	if s.keep > 0 && version >= uint64(s.keep) {
+		s.store.Delete(diffKey(version - uint64(s.keep)))
+	}
+	s.pending = make(map[string]*pendingContract)
+	return s.Root(), nil
+}
+
+// replacedContractStates returns the hashes of the contract states of
+// the given version that the next version replaced, which no other
+// version has once the given one is pruned.
+func (s *State) replacedContractStates(version uint64) []*big.Int {
+	old, ok := s.global.At(version)
+	if !ok {
+		return nil
+	}
+	diff, _ := s.store.Get(diffKey(version + 1))
+	hashes := make([]*big.Int, 0, len(diff)/feltLen)
+	for i := 0; i+feltLen <= len(diff); i += feltLen {
+		if hash, ok := old.Get(new(big.Int).SetBytes(diff[i : i+feltLen])); ok {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// At returns the state as it was recorded at the given version, and
+// false if there is no such version. The returned state is read only.
+func (s *State) At(version uint64) (*State, bool) {
+	global, ok := s.global.At(version)
+	if !ok {
+		return nil, false
+	}
+	return &State{store: s.store, keep: s.keep, global: global, readOnly: true}, true
+}
+
+// Revert undoes the given version, which must be the latest one, so the
+// state is again as it was at the previous version, and drops it. The
+// pending changes are discarded. An error is returned, and nothing is
+// reverted, if the version or the previous one are not kept.
+func (s *State) Revert(version uint64) error {
+	if _, ok := s.At(version); !ok {
+		return fmt.Errorf("state version %d not found", version)
+	}
+	previous := &State{store: s.store, global: s.global.AtRoot(new(big.Int)), readOnly: true}
+	if version > 0 {
+		var ok bool
+		if previous, ok = s.At(version - 1); !ok {
+			return fmt.Errorf("state version %d not found", version-1)
+		}
+	}
+
+	diff, _ := s.store.Get(diffKey(version))
+	roots := make([]*big.Int, 0, len(diff)/feltLen)
+	for i := 0; i+feltLen <= len(diff); i += feltLen {
+		address := new(big.Int).SetBytes(diff[i : i+feltLen])
+		root := new(big.Int)
+		if contract, ok := previous.ContractState(address); ok {
+			root = contract.StorageRoot
+		} else if _, ok := previous.global.Get(address); ok {
+			return fmt.Errorf("state of contract %x unknown at version %d", address, version-1)
+		}
+		roots = append(roots, root)
+	}
+
+	for i, root := range roots {
+		address := new(big.Int).SetBytes(diff[i*feltLen : (i+1)*feltLen])
+		if hash, ok := s.global.Get(address); ok {
+			s.releaseContractState(hash)
+		}
+		storageTrie := s.storageTrie(address)
+		storageTrie.Reset(root)
+		storageTrie.DropVersion(version)
+	}
+	s.global.Reset(previous.Root())
+	s.global.DropVersion(version)
+	s.store.Delete(diffKey(version))
+	s.pending = make(map[string]*pendingContract)
+	return nil
+}
+
+// storageTrie returns the storage trie of the contract with the given
+// address.
+func (s *State) storageTrie(address *big.Int) trie.Trie {
+	prefix := []byte("storage:" + address.Text(16) + ":")
+	return trie.NewWithPruning(prefixStore{s.store, prefix}, height, s.keep)
+}
+
+// contractKey returns the key of the contract state with the given hash.
+func contractKey(hash *big.Int) []byte {
+	return append([]byte("contract:"), hash.FillBytes(make([]byte, feltLen))...)
+}
+
+// diffKey returns the key of the addresses of the contracts changed by
+// the given version.
+func diffKey(version uint64) []byte {
+	key := make([]byte, len("diff:")+8)
+	copy(key, "diff:")
+	binary.BigEndian.PutUint64(key[len("diff:"):], version)
+	return key
+}
+
+// prefixStore is a store.Storer that keeps its keys in another store
+// with the given prefix.
+type prefixStore struct {
+	store  store.Storer
+	prefix []byte
+}
+
+func (p prefixStore) key(key []byte) []byte {
+	return append(append(make([]byte, 0, len(p.prefix)+len(key)), p.prefix...), key...)
+}
+
+func (p prefixStore) Delete(key []byte) {
+	p.store.Delete(p.key(key))
+}
+
+func (p prefixStore) Get(key []byte) ([]byte, bool) {
+	return p.store.Get(p.key(key))
+}
+
+func (p prefixStore) Put(key, val []byte) {
+	p.store.Put(p.key(key), val)
+}
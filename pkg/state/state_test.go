@@ -0,0 +1,287 @@
+package state
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+// hexInt returns the *big.Int with the given hex representation.
+func hexInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 16)
+	return i
+}
+
+// genesisStorage is the storage of the contracts deployed in the block 0
+// of the StarkNet mainnet, by address.
+//
+// See https://alpha-mainnet.starknet.io/feeder_gateway/get_state_update?blockNumber=0.
+var genesisStorage = map[string]map[string]string{
+	"735596016a37ee972c42adef6a3cf628c19bb3794369c65d2c82ba034aecf2c": {
+		"5": "64",
+		"2f50710449a06a9fa789b3c029a63bd0b1f722f46505828a9f815cf91b31d8": "2a222e62eabe91abdb6838fa8b267ffe81a6eb575f61e96ec9aa4460c0925a2",
+	},
+	"20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6": {
+		"5": "22b",
+		"5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5": "7e5",
+		"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620300": "4e7e989d58a17cd279eca440c5eaa829efb6f9967aaad89022acbe644c39b36",
+		"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620301": "453ae0c9610197b18b13645c44d3d0a407083d96562e8752aab3fab616cecb0",
+		"6cf6c2f36d36b08e591e4489e92ca882bb67b9c39a3afccf011972a8de467f0": "7ab344d88124307c07b56f6c59c12f4543e9c96398727854a322dea82c73240",
+	},
+	"6ee3440b08a9c805305449ec7f7003f27e9f7e287b83610952ec36bdc5a6bae": {
+		"1e2cd4b3588e8f6f9c4e89fb0e293bf92018c96d7a93ee367d29a284223b6ff": "71d1e9d188c784a0bde95c1d508877a0d93e9102b37213d1e13f3ebc54a7751",
+		"5f750dc13ed239fa6fc43ff6e10ae9125a33bd05ec034fc3bb4dd168df3505f": "7e5",
+		"48cba68d4e86764105adcdcf641ab67b581a55a4f367203647549c8bf1feea2": "362d24a3b030998ac75e838955dfee19ec5b6eceb235b9bfbeccf51b6304d0b",
+		"449908c349e90f81ab13042b1e49dc251eb6e3e51092d9a40f86859f7f415b0": "6cb6104279e754967a721b52bcf5be525fdc11fa6db6ef5c3a4db832acf7804",
+		"5bdaf1d47b176bfcd1114809af85a46b9c4376e87e361d86536f0288a284b65": "28dff6722aa73281b2cf84cac09950b71fa90512db294d2042119abdd9f4b87",
+		"5bdaf1d47b176bfcd1114809af85a46b9c4376e87e361d86536f0288a284b66": "57a8f8a019ccab5bfc6ff86c96b1392257abb8d5d110c01d326b94247af161c",
+	},
+	"31c887d82502ceb218c06ebb46198da3f7b92864a8223746bc836dda3e34b52": {
+		"5f750dc13ed239fa6fc43ff6e10ae9125a33bd05ec034fc3bb4dd168df3505f": "7c7",
+		"df28e613c065616a2e79ca72f9c1908e17b8c913972a9993da77588dc9cae9":  "1432126ac23c7028200e443169c2286f99cdb5a7bf22e607bcd724efa059040",
+	},
+	"31c9cdb9b00cb35cf31c05855c0ec3ecf6f7952a1ce6e3c53c3455fcd75a280": {
+		"5": "65",
+		"5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5": "7c7",
+		"cfc2e2866fd08bfb4ac73b70e0c136e326ae18fc797a2c090c8811c695577e":  "5f1dd5a5aef88e0498eeca4e7b2ea0fa7110608c11531278742f0b5499af4b3",
+		"5fac6815fddf6af1ca5e592359862ede14f171e1544fd9e792288164097c35d": "299e2f4b5a873e95e65eb03d31e532ea2cde43b498b50cd3161145db5542a5",
+		"5fac6815fddf6af1ca5e592359862ede14f171e1544fd9e792288164097c35e": "3d6897cf23da3bf4fd35cc7a43ccaf7c5eaf8f7c5b9031ac9b09a929204175f",
+	},
+}
+
+// genesisClassHash is the class hash of every contract deployed in the
+// block 0 of the StarkNet mainnet.
+var genesisClassHash = hexInt("10455c752b86932ce552f2b0fe81a880746649b9aee7e0d842bf3f52378f9f8")
+
+// genesisState returns a state with the contracts of the block 0 of the
+// StarkNet mainnet committed as the version 0.
+func genesisState(t *testing.T, s *State) *big.Int {
+	for address, storage := range genesisStorage {
+		s.SetClassHash(hexInt(address), genesisClassHash)
+		for key, value := range storage {
+			s.SetStorage(hexInt(address), hexInt(key), hexInt(value))
+		}
+	}
+	root, err := s.Commit(0)
+	if err != nil {
+		t.Fatalf("unexpected error committing the genesis state: %s", err)
+	}
+	return root
+}
+
+// TestState tests whether the state produces the same state root as in
+// Block 0 of the StarkNet protocol mainnet.
+func TestState(t *testing.T) {
+	s := New(store.New())
+	root := genesisState(t, s)
+
+	want := hexInt("021870ba80540e7831fb21c591ee93481f5ae1bb71ff85a86ddd465be4eddee6")
+	if root.Cmp(want) != 0 {
+		t.Errorf("state root %x, want %x", root, want)
+	}
+	for address, storage := range genesisStorage {
+		contract, ok := s.ContractState(hexInt(address))
+		if !ok {
+			t.Fatalf("contract %s not found", address)
+		}
+		if contract.ClassHash.Cmp(genesisClassHash) != 0 || contract.Nonce.Sign() != 0 {
+			t.Errorf("unexpected state %v of contract %s", contract, address)
+		}
+		for key, value := range storage {
+			if got := s.GetStorage(hexInt(address), hexInt(key)); got.Cmp(hexInt(value)) != 0 {
+				t.Errorf("storage %s of contract %s = %x, want %s", key, address, got, value)
+			}
+		}
+	}
+	if _, ok := s.ContractState(big.NewInt(1)); ok {
+		t.Errorf("unknown contract found")
+	}
+}
+
+func TestState_CommitWithoutClassHash(t *testing.T) {
+	s := New(store.New())
+	s.SetStorage(big.NewInt(1), big.NewInt(2), big.NewInt(3))
+	if _, err := s.Commit(0); err == nil {
+		t.Fatalf("expected an error committing a contract without class hash")
+	}
+	if s.Root().Sign() != 0 {
+		t.Errorf("failed commit changed the state root")
+	}
+	if _, err := s.Commit(0); err != nil || s.Root().Sign() != 0 {
+		t.Errorf("failed commit kept the pending changes")
+	}
+}
+
+func TestState_Versions(t *testing.T) {
+	s := New(store.New())
+	address := hexInt("20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6")
+	deployed := big.NewInt(0xdead)
+	genesisRoot := genesisState(t, s)
+
+	// The version 1 changes a key, deletes another one, bumps a nonce and
+	// deploys a new contract.
+	s.SetStorage(address, big.NewInt(5), big.NewInt(0x22c))
+	s.SetStorage(address, hexInt("5aee31408163292105d875070f98cb48275b8c87e80380b78d30647e05854d5"), new(big.Int))
+	s.SetNonce(address, big.NewInt(1))
+	s.SetClassHash(deployed, big.NewInt(1))
+	s.SetStorage(deployed, big.NewInt(1), big.NewInt(1))
+	root, err := s.Commit(1)
+	if err != nil {
+		t.Fatalf("unexpected error committing the version 1: %s", err)
+	}
+	if root.Cmp(genesisRoot) == 0 {
+		t.Fatalf("version 1 has the genesis root")
+	}
+
+	genesis, ok := s.At(0)
+	if !ok {
+		t.Fatalf("version 0 not found")
+	}
+	if genesis.Root().Cmp(genesisRoot) != 0 {
+		t.Errorf("version 0 has root %x, want %x", genesis.Root(), genesisRoot)
+	}
+	if got := genesis.GetStorage(address, big.NewInt(5)); got.Int64() != 0x22b {
+		t.Errorf("storage 5 at version 0 = %x, want 22b", got)
+	}
+	if got := s.GetStorage(address, big.NewInt(5)); got.Int64() != 0x22c {
+		t.Errorf("storage 5 at version 1 = %x, want 22c", got)
+	}
+	if contract, ok := s.ContractState(address); !ok || contract.Nonce.Int64() != 1 {
+		t.Errorf("unexpected state %v of contract %x at version 1", contract, address)
+	}
+	if _, ok := genesis.ContractState(deployed); ok {
+		t.Errorf("contract deployed at version 1 found at version 0")
+	}
+
+	if err := s.Revert(1); err != nil {
+		t.Fatalf("unexpected error reverting the version 1: %s", err)
+	}
+	if s.Root().Cmp(genesisRoot) != 0 {
+		t.Errorf("reverted root %x, want %x", s.Root(), genesisRoot)
+	}
+	if got := s.GetStorage(address, big.NewInt(5)); got.Int64() != 0x22b {
+		t.Errorf("storage 5 after revert = %x, want 22b", got)
+	}
+	if got := s.GetStorage(deployed, big.NewInt(1)); got.Sign() != 0 {
+		t.Errorf("storage of a reverted contract = %x, want 0", got)
+	}
+	if _, ok := s.At(1); ok {
+		t.Errorf("reverted version found")
+	}
+	if err := s.Revert(1); err == nil {
+		t.Errorf("expected an error reverting a missing version")
+	}
+}
+
+func TestState_Pruning(t *testing.T) {
+	s := NewWithPruning(store.New(), 2)
+	address := big.NewInt(1)
+	// The contract 2 is deployed at version 0 and never changed, and the
+	// contract 3 has at version 3 the state the contract 1 had at version 0.
+	unchanged, copied := big.NewInt(2), big.NewInt(3)
+	roots := make([]*big.Int, 4)
+	hashes := make([]*big.Int, 4)
+	for version := range roots {
+		s.SetClassHash(address, big.NewInt(1))
+		s.SetStorage(address, big.NewInt(1), big.NewInt(int64(version+1)))
+		switch version {
+		case 0:
+			s.SetClassHash(unchanged, big.NewInt(2))
+		case 3:
+			s.SetClassHash(copied, big.NewInt(1))
+			s.SetStorage(copied, big.NewInt(1), big.NewInt(1))
+		}
+		root, err := s.Commit(uint64(version))
+		if err != nil {
+			t.Fatalf("unexpected error committing the version %d: %s", version, err)
+		}
+		roots[version] = root
+		hashes[version], _ = s.global.Get(address)
+	}
+
+	// The contract states of the pruned versions are deleted, unless a
+	// kept version has them too.
+	for version, hash := range hashes {
+		_, ok := s.contractState(hash)
+		if want := version != 1; ok != want {
+			t.Errorf("contract state of version %d found: %t, want %t", version, ok, want)
+		}
+	}
+	if _, ok := s.ContractState(unchanged); !ok {
+		t.Errorf("state of the unchanged contract not found")
+	}
+
+	for version, root := range roots {
+		old, ok := s.At(uint64(version))
+		if version < 2 {
+			if ok {
+				t.Errorf("pruned version %d found", version)
+			}
+			continue
+		}
+		if !ok || old.Root().Cmp(root) != 0 {
+			t.Errorf("version %d not kept", version)
+			continue
+		}
+		if got := old.GetStorage(address, big.NewInt(1)); got.Int64() != int64(version+1) {
+			t.Errorf("storage at version %d = %d, want %d", version, got, version+1)
+		}
+	}
+
+	// The latest kept version can be reverted, but not the oldest one.
+	if err := s.Revert(3); err != nil {
+		t.Errorf("unexpected error reverting the version 3: %s", err)
+	}
+	if _, ok := s.contractState(hashes[3]); ok {
+		t.Errorf("contract state of the reverted version found")
+	}
+	if _, ok := s.contractState(hashes[0]); ok {
+		t.Errorf("contract state of the pruned version found after revert")
+	}
+	if err := s.Revert(2); err == nil {
+		t.Errorf("expected an error reverting past the kept versions")
+	}
+}
+
+func TestState_StorageIterator(t *testing.T) {
+	s := New(store.New())
+	genesisState(t, s)
+	address := hexInt("20cfa74ee3564b4cd5435cdace0f9c4d43b939620e4a0bb5076105df0a626c6")
+	s.SetStorage(address, big.NewInt(5), new(big.Int))
+	if _, err := s.Commit(1); err != nil {
+		t.Fatalf("unexpected error committing the version 1: %s", err)
+	}
+
+	genesis, _ := s.At(0)
+	for version, state := range []*State{genesis, s} {
+		storage := make(map[string]string)
+		for key, value := range genesisStorage[address.Text(16)] {
+			storage[key] = value
+		}
+		if version == 1 {
+			delete(storage, "5")
+		}
+
+		it := state.StorageIterator(address, nil, nil)
+		var previous *big.Int
+		for it.Next() {
+			key := it.Key().Text(16)
+			if want, ok := storage[key]; !ok || it.Value().Cmp(hexInt(want)) != 0 {
+				t.Errorf("version %d: unexpected storage %s = %x", version, key, it.Value())
+			}
+			if previous != nil && previous.Cmp(it.Key()) >= 0 {
+				t.Errorf("version %d: key %s after %x", version, key, previous)
+			}
+			previous = it.Key()
+			delete(storage, key)
+		}
+		if len(storage) != 0 {
+			t.Errorf("version %d: storage keys %v not found", version, storage)
+		}
+	}
+
+	if it := s.StorageIterator(big.NewInt(1), nil, nil); it.Next() {
+		t.Errorf("unknown contract has storage key %x", it.Key())
+	}
+}
@@ -0,0 +1,102 @@
+package trie
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+// Batch accumulates updates to a trie and applies them at once with
+// Commit. Unlike Trie.Put and Trie.Delete, which build a new node for
+// every node from the leaf to the root on each call, a batch walks the
+// trie once for all the updates, so the nodes shared by the paths of
+// the updated keys are hashed and written once per batch.
+//
+// The nodes and reference counts written while the batch is committed
+// are kept in memory and flushed to the store at the end of Commit, so
+// each of them is written once per batch, and not at all if it ends as
+// it was, like the reference counts of the children shared by the old
+// and the new nodes.
+//
+// The updates are not visible through the trie until the batch is
+// committed.
+type Batch struct {
+	trie *Trie
+	// updates are the values of the updated keys by key. A zero value
+	// deletes the key.
+	updates map[string]update
+}
+
+// NewBatch returns an empty batch of updates to the trie.
+func (t *Trie) NewBatch() *Batch {
+	return &Batch{trie: t, updates: make(map[string]update)}
+}
+
+// Put sets the value of the key when the batch is committed. A zero
+// value deletes the key.
+func (b *Batch) Put(key, val *big.Int) {
+	b.updates[string(key.Bytes())] = update{new(big.Int).Set(key), new(big.Int).Set(val)}
+}
+
+// Delete removes the key when the batch is committed.
+func (b *Batch) Delete(key *big.Int) {
+	b.Put(key, new(big.Int))
+}
+
+// Commit applies the updates of the batch to the trie and empties the
+// batch.
+func (b *Batch) Commit() {
+	if len(b.updates) == 0 {
+		return
+	}
+	updates := make([]update, 0, len(b.updates))
+	for _, u := range b.updates {
+		updates = append(updates, u)
+	}
+	// The updates are applied to a copy of the trie that writes to the
+	// dirty nodes, as the trie only keeps its state in the store.
+	dirty := &dirtyNodes{store: b.trie.store, nodes: make(map[string][]byte)}
+	t := *b.trie
+	t.store = dirty
+	t.apply(updates)
+	dirty.flush()
+	b.updates = make(map[string]update)
+}
+
+// dirtyNodes is a store.Storer that keeps the writes to another store in
+// memory until they are flushed.
+type dirtyNodes struct {
+	store store.Storer
+	// nodes are the values written by key, nil for a deleted key.
+	nodes map[string][]byte
+}
+
+func (d *dirtyNodes) Get(key []byte) ([]byte, bool) {
+	if val, ok := d.nodes[string(key)]; ok {
+		return val, val != nil
+	}
+	return d.store.Get(key)
+}
+
+func (d *dirtyNodes) Put(key, val []byte) {
+	d.nodes[string(key)] = val
+}
+
+func (d *dirtyNodes) Delete(key []byte) {
+	d.nodes[string(key)] = nil
+}
+
+// flush writes the values that changed to the store.
+func (d *dirtyNodes) flush() {
+	for key, val := range d.nodes {
+		old, ok := d.store.Get([]byte(key))
+		switch {
+		case val == nil && ok:
+			d.store.Delete([]byte(key))
+		case val != nil && (!ok || !bytes.Equal(old, val)):
+			d.store.Put([]byte(key), val)
+		}
+	}
+	d.nodes = make(map[string][]byte)
+}
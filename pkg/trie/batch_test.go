@@ -0,0 +1,156 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// mapStore is a store.Storer whose content can be compared.
+type mapStore map[string][]byte
+
+func (s mapStore) Delete(key []byte) {
+	delete(s, string(key))
+}
+
+func (s mapStore) Get(key []byte) ([]byte, bool) {
+	val, ok := s[string(key)]
+	return val, ok
+}
+
+func (s mapStore) Put(key, val []byte) {
+	s[string(key)] = val
+}
+
+// equalStores reports whether the given stores have the same content.
+func equalStores(a, b mapStore) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, val := range a {
+		if other, ok := b[key]; !ok || !bytes.Equal(val, other) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBatch(t *testing.T) {
+	for _, keyLen := range []int{testKeyLen, 251} {
+		t.Run(fmt.Sprintf("key length %d", keyLen), func(t *testing.T) {
+			want := New(mapStore{}, keyLen)
+			got := New(mapStore{}, keyLen)
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			bound := new(big.Int).Lsh(big.NewInt(1), uint(keyLen))
+
+			// Every batch updates new keys, overwrites and deletes keys of
+			// the previous batches and sets keys more than once.
+			var keys []*big.Int
+			for round := 0; round < 3; round++ {
+				batch := got.NewBatch()
+				for i := 0; i < 8; i++ {
+					var key *big.Int
+					if len(keys) > 0 && rng.Intn(2) == 0 {
+						key = keys[rng.Intn(len(keys))]
+					} else {
+						key = new(big.Int).Rand(rng, bound)
+						keys = append(keys, key)
+					}
+					val := big.NewInt(int64(rng.Intn(3)))
+					want.Put(key, val)
+					batch.Put(key, val)
+				}
+				if len(keys) > 0 {
+					key := keys[rng.Intn(len(keys))]
+					want.Delete(key)
+					batch.Delete(key)
+				}
+				batch.Commit()
+
+				if got.Commitment().Cmp(want.Commitment()) != 0 {
+					t.Fatalf("round %d: batch commitment %x, want %x", round, got.Commitment(), want.Commitment())
+				}
+				if !equalStores(got.store.(mapStore), want.store.(mapStore)) {
+					t.Fatalf("round %d: the batch stored different nodes", round)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchNotVisibleBeforeCommit(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	batch := trie.NewBatch()
+	for _, test := range tests {
+		batch.Put(test.key, test.val)
+	}
+	if trie.Commitment().Sign() != 0 {
+		t.Errorf("uncommitted batch changed the commitment")
+	}
+	batch.Commit()
+	for _, test := range tests {
+		got, ok := trie.Get(test.key)
+		if test.val.Sign() == 0 {
+			if ok {
+				t.Errorf("get(%#v) = %#v, want no value", test.key, got)
+			}
+			continue
+		}
+		if !ok || got.Cmp(test.val) != 0 {
+			t.Errorf("get(%#v) = %#v, want %#v", test.key, got, test.val)
+		}
+	}
+
+	// Deleting every key empties the trie.
+	batch = trie.NewBatch()
+	for _, test := range tests {
+		batch.Delete(test.key)
+	}
+	batch.Commit()
+	if trie.Commitment().Sign() != 0 || len(trie.store.(mapStore)) != 0 {
+		t.Errorf("trie not empty after deleting every key")
+	}
+}
+
+// writeCountingStore is a mapStore that counts the writes to each key.
+type writeCountingStore struct {
+	mapStore
+	writes map[string]int
+}
+
+func (s *writeCountingStore) Delete(key []byte) {
+	s.writes[string(key)]++
+	s.mapStore.Delete(key)
+}
+
+func (s *writeCountingStore) Put(key, val []byte) {
+	s.writes[string(key)]++
+	s.mapStore.Put(key, val)
+}
+
+func TestBatchWritesOnce(t *testing.T) {
+	store := &writeCountingStore{mapStore{}, make(map[string]int)}
+	trie := New(store, testKeyLen)
+	want := New(mapStore{}, testKeyLen)
+	for round := 0; round < 2; round++ {
+		store.writes = make(map[string]int)
+		batch := trie.NewBatch()
+		for _, test := range tests {
+			val := new(big.Int).Add(test.val, big.NewInt(int64(round)))
+			batch.Put(test.key, val)
+			want.Put(test.key, val)
+		}
+		batch.Commit()
+
+		for key, n := range store.writes {
+			if n > 1 {
+				t.Errorf("round %d: key %x written %d times", round, key, n)
+			}
+		}
+		if !equalStores(store.mapStore, want.store.(mapStore)) {
+			t.Errorf("round %d: the batch stored different nodes", round)
+		}
+	}
+}
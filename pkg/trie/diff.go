@@ -0,0 +1,70 @@
+package trie
+
+import "math/big"
+
+// Change is a leaf that differs between two tries. Old is nil for a key
+// added by the second trie, and New is nil for a key it removed.
+type Change struct {
+	Key *big.Int
+	Old *big.Int
+	New *big.Int
+}
+
+// Diff returns the leaves that differ from the trie from to the trie to,
+// which must have the same key length, in increasing key order. The
+// tries may be versions of the same trie, as returned by At or AtRoot,
+// or tries on different stores. The subtrees that are equal in both
+// tries are skipped, so the nodes read are only those on the paths of
+// the changed keys.
+func Diff(from, to *Trie) []Change {
+	var changes []Change
+	diff(from, to, 0, from.rootSubtree(), to.rootSubtree(), new(big.Int), &changes)
+	return changes
+}
+
+// rootSubtree returns the subtree of the root of the trie, or nil if it's
+// empty.
+func (t *Trie) rootSubtree() *Node {
+	root, ok := t.root(t.rootKey)
+	if !ok {
+		return nil
+	}
+	return t.subtree(0, root)
+}
+
+// diff appends to changes the leaves that differ between the subtrees a
+// of the trie from and b of the trie to, at the given height, whose
+// keys start with the given prefix. A nil node is an empty subtree.
+func diff(from, to *Trie, height int, a, b *Node, prefix *big.Int, changes *[]Change) {
+	if sameSubtree(a, b) {
+		return
+	}
+	if height == from.keyLen {
+		change := Change{Key: prefix}
+		if a != nil {
+			change.Old = a.Bottom
+		}
+		if b != nil {
+			change.New = b.Bottom
+		}
+		*changes = append(*changes, change)
+		return
+	}
+
+	aLeft, aRight := from.children(height, a)
+	bLeft, bRight := to.children(height, b)
+	prefix = new(big.Int).Lsh(prefix, 1)
+	diff(from, to, height+1, aLeft, bLeft, prefix, changes)
+	diff(from, to, height+1, aRight, bRight, new(big.Int).Add(prefix, big.NewInt(1)), changes)
+}
+
+// sameSubtree reports whether the given subtrees at the same height are
+// equal. The encoding of a subtree determines its hash and the other
+// way around, so the subtrees are compared by encoding, which needs no
+// hashing.
+func sameSubtree(a, b *Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Length == b.Length && a.Path.Cmp(b.Path) == 0 && a.Bottom.Cmp(b.Bottom) == 0
+}
@@ -0,0 +1,138 @@
+package trie
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// countingStore is a mapStore that counts the reads.
+type countingStore struct {
+	mapStore
+	reads int
+}
+
+func (s *countingStore) Get(key []byte) ([]byte, bool) {
+	s.reads++
+	return s.mapStore.Get(key)
+}
+
+// sameValue reports whether the given values, which may be nil, are
+// equal.
+func sameValue(a, b *big.Int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(b) == 0
+}
+
+// leaves returns the leaves of the trie by key.
+func leaves(t *Trie) map[string]*big.Int {
+	pairs := make(map[string]*big.Int)
+	for it := t.NewIterator(nil, nil); it.Next(); {
+		pairs[it.Key().Text(16)] = it.Value()
+	}
+	return pairs
+}
+
+func TestDiff(t *testing.T) {
+	for _, keyLen := range []int{testKeyLen, 251} {
+		t.Run(fmt.Sprintf("key length %d", keyLen), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			bound := new(big.Int).Lsh(big.NewInt(1), uint(keyLen))
+			trie := New(mapStore{}, keyLen)
+
+			// The version 2 adds, changes and removes keys of the version 1.
+			var keys []*big.Int
+			for version := uint64(1); version <= 2; version++ {
+				batch := trie.NewBatch()
+				for i := 0; i < 12; i++ {
+					key := new(big.Int).Rand(rng, bound)
+					if version == 2 && rng.Intn(2) == 0 {
+						key = keys[rng.Intn(len(keys))]
+					}
+					keys = append(keys, key)
+					batch.Put(key, big.NewInt(rng.Int63n(3)))
+				}
+				batch.Commit()
+				trie.Snapshot(version)
+			}
+			from, _ := trie.At(1)
+			to, _ := trie.At(2)
+
+			// The changes are the differences between the leaves.
+			fromLeaves, toLeaves := leaves(&from), leaves(&to)
+			changes := Diff(&from, &to)
+			for i, change := range changes {
+				if i > 0 && changes[i-1].Key.Cmp(change.Key) >= 0 {
+					t.Errorf("change of key %x after key %x", change.Key, changes[i-1].Key)
+				}
+				key := change.Key.Text(16)
+				oldVal, newVal := fromLeaves[key], toLeaves[key]
+				if !sameValue(change.Old, oldVal) || !sameValue(change.New, newVal) {
+					t.Errorf("change of key %s from %v to %v, want from %v to %v", key, change.Old, change.New, oldVal, newVal)
+				}
+				delete(fromLeaves, key)
+				delete(toLeaves, key)
+			}
+			for key, oldVal := range fromLeaves {
+				if newVal := toLeaves[key]; !sameValue(oldVal, newVal) {
+					t.Errorf("change of key %s from %v to %v not found", key, oldVal, newVal)
+				}
+				delete(toLeaves, key)
+			}
+			for key := range toLeaves {
+				t.Errorf("added key %s not found", key)
+			}
+
+			// The diff is the other way around in reverse.
+			reverse := Diff(&to, &from)
+			if len(reverse) != len(changes) {
+				t.Fatalf("reverse diff has %d changes, want %d", len(reverse), len(changes))
+			}
+			for i := range reverse {
+				if reverse[i].Key.Cmp(changes[i].Key) != 0 || !sameValue(reverse[i].Old, changes[i].New) ||
+					!sameValue(reverse[i].New, changes[i].Old) {
+					t.Errorf("reverse change %v, want the reverse of %v", reverse[i], changes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDiffSkipsEqualSubtrees(t *testing.T) {
+	store := &countingStore{mapStore: mapStore{}}
+	trie := New(store, 251)
+	rng := rand.New(rand.NewSource(rand.Int63()))
+	bound := new(big.Int).Lsh(big.NewInt(1), 251)
+	batch := trie.NewBatch()
+	var keys []*big.Int
+	for i := 0; i < 64; i++ {
+		key := new(big.Int).Rand(rng, bound)
+		keys = append(keys, key)
+		batch.Put(key, big.NewInt(1))
+	}
+	batch.Commit()
+	trie.Snapshot(1)
+	trie.Put(keys[0], big.NewInt(2))
+
+	from, _ := trie.At(1)
+	store.reads = 0
+	changes := Diff(&from, &trie)
+	if len(changes) != 1 || changes[0].Key.Cmp(keys[0]) != 0 || changes[0].Old.Int64() != 1 || changes[0].New.Int64() != 2 {
+		t.Fatalf("unexpected changes %v, want the key %x changed from 1 to 2", changes, keys[0])
+	}
+	// Reading the 127 nodes of each trie would take 254 reads.
+	if store.reads > 60 {
+		t.Errorf("diff of a single key took %d reads", store.reads)
+	}
+
+	if changes := Diff(&trie, &trie); len(changes) != 0 {
+		t.Errorf("unexpected changes %v between a trie and itself", changes)
+	}
+	empty := New(mapStore{}, 251)
+	if changes := Diff(&empty, &trie); len(changes) != len(keys) {
+		t.Errorf("diff from the empty trie has %d changes, want %d", len(changes), len(keys))
+	}
+}
@@ -0,0 +1,105 @@
+package trie
+
+import "math/big"
+
+// Iterator walks the leaves of a trie in increasing key order. It's
+// used like a bufio.Scanner:
+//
+//	it := t.NewIterator(nil, nil)
+//	for it.Next() {
+//		key, val := it.Key(), it.Value()
+//		...
+//	}
+//
+// The nodes are read from the store as the iterator advances, so the
+// trie must not be updated until the iteration ends. The versions of a
+// trie don't change, so a trie returned by At or AtRoot can always be
+// iterated.
+type Iterator struct {
+	trie     *Trie
+	start    *big.Int
+	end      *big.Int
+	stack    []iteratorFrame
+	key, val *big.Int
+}
+
+// iteratorFrame is a subtree left to visit: the node or leaf with the
+// given height and hash, whose key starts with the given prefix of
+// height bits.
+type iteratorFrame struct {
+	height int
+	hash   *big.Int
+	prefix *big.Int
+}
+
+// NewIterator returns an iterator over the keys of the trie from start,
+// included, to end, excluded. A nil start begins at the first key and a
+// nil end runs to the last one.
+func (t *Trie) NewIterator(start, end *big.Int) *Iterator {
+	it := &Iterator{trie: t, start: start, end: end}
+	if root, ok := t.root(t.rootKey); ok {
+		it.push(0, root, new(big.Int))
+	}
+	return it
+}
+
+// Next advances the iterator to the next leaf, which is then available
+// through the Key and Value methods. It returns false when there are no
+// more leaves.
+func (it *Iterator) Next() bool {
+	for len(it.stack) > 0 {
+		f := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		if f.height == it.trie.keyLen {
+			it.key, it.val = f.prefix, f.hash
+			return true
+		}
+
+		n, _ := it.trie.mustLoad(f.height, f.hash)
+		switch n := n.(type) {
+		case *BinaryNode:
+			// The right subtree is pushed first so the left one is
+			// visited first.
+			prefix := new(big.Int).Lsh(f.prefix, 1)
+			it.push(f.height+1, n.RightHash, new(big.Int).Add(prefix, big.NewInt(1)))
+			it.push(f.height+1, n.LeftHash, prefix)
+		case *EdgeNode:
+			prefix := new(big.Int).Lsh(f.prefix, uint(n.Length))
+			it.push(f.height+int(n.Length), n.Bottom, prefix.Add(prefix, n.Path))
+		}
+	}
+	it.key, it.val = nil, nil
+	return false
+}
+
+// push adds the given subtree to the ones left to visit, unless none of
+// its keys is in the range of the iterator.
+func (it *Iterator) push(height int, hash, prefix *big.Int) {
+	shift := uint(it.trie.keyLen - height)
+	if it.start != nil {
+		// next is the key that follows the last key of the subtree.
+		next := new(big.Int).Add(prefix, big.NewInt(1))
+		next.Lsh(next, shift)
+		if next.Cmp(it.start) <= 0 {
+			return
+		}
+	}
+	if it.end != nil {
+		first := new(big.Int).Lsh(prefix, shift)
+		if first.Cmp(it.end) >= 0 {
+			return
+		}
+	}
+	it.stack = append(it.stack, iteratorFrame{height, hash, prefix})
+}
+
+// Key returns the key of the current leaf.
+func (it *Iterator) Key() *big.Int {
+	return it.key
+}
+
+// Value returns the value of the current leaf.
+func (it *Iterator) Value() *big.Int {
+	return it.val
+}
@@ -0,0 +1,101 @@
+package trie
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// collect returns the keys and values visited by the iterator.
+func collect(it *Iterator) (keys, vals []*big.Int) {
+	for it.Next() {
+		keys = append(keys, it.Key())
+		vals = append(vals, it.Value())
+	}
+	return keys, vals
+}
+
+func TestIterator(t *testing.T) {
+	for _, keyLen := range []int{testKeyLen, 251} {
+		t.Run(fmt.Sprintf("key length %d", keyLen), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(rand.Int63()))
+			bound := new(big.Int).Lsh(big.NewInt(1), uint(keyLen))
+			trie := New(mapStore{}, keyLen)
+			pairs := make(map[string]*big.Int)
+			batch := trie.NewBatch()
+			for i := 0; i < 16; i++ {
+				key := new(big.Int).Rand(rng, bound)
+				val := big.NewInt(rng.Int63n(100) + 1)
+				batch.Put(key, val)
+				pairs[key.Text(16)] = val
+			}
+			batch.Commit()
+			var keys []*big.Int
+			for k := range pairs {
+				key, _ := new(big.Int).SetString(k, 16)
+				keys = append(keys, key)
+			}
+			sort.Slice(keys, func(i, j int) bool { return keys[i].Cmp(keys[j]) < 0 })
+
+			ranges := [][2]*big.Int{{nil, nil}}
+			for i := 0; i < 8; i++ {
+				start := new(big.Int).Rand(rng, bound)
+				end := new(big.Int).Rand(rng, bound)
+				ranges = append(ranges, [2]*big.Int{start, end}, [2]*big.Int{start, nil}, [2]*big.Int{nil, end})
+			}
+			// The bounds may be keys of the trie too.
+			ranges = append(ranges, [2]*big.Int{keys[1], keys[len(keys)-2]})
+
+			for _, r := range ranges {
+				start, end := r[0], r[1]
+				var want []*big.Int
+				for _, key := range keys {
+					if (start == nil || key.Cmp(start) >= 0) && (end == nil || key.Cmp(end) < 0) {
+						want = append(want, key)
+					}
+				}
+				got, vals := collect(trie.NewIterator(start, end))
+				if len(got) != len(want) {
+					t.Fatalf("range [%v, %v): got %d keys, want %d", start, end, len(got), len(want))
+				}
+				for i := range want {
+					if got[i].Cmp(want[i]) != 0 || vals[i].Cmp(pairs[want[i].Text(16)]) != 0 {
+						t.Errorf("range [%v, %v): got key %x with value %v, want key %x with value %v",
+							start, end, got[i], vals[i], want[i], pairs[want[i].Text(16)])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIteratorEmptyTrie(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	it := trie.NewIterator(nil, nil)
+	if it.Next() {
+		t.Errorf("iterator of an empty trie found key %v", it.Key())
+	}
+}
+
+func TestIteratorVersion(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	putVersion(&trie, 1, 1)
+	for _, test := range tests {
+		trie.Delete(test.key)
+	}
+
+	old, ok := trie.At(1)
+	if !ok {
+		t.Fatalf("version 1 not found")
+	}
+	keys, _ := collect(old.NewIterator(nil, nil))
+	// The keys 2, 3 and 5 have a value.
+	if len(keys) != 3 || keys[0].Int64() != 2 || keys[1].Int64() != 3 || keys[2].Int64() != 5 {
+		t.Errorf("unexpected keys %v at version 1, want [2 3 5]", keys)
+	}
+	if keys, _ := collect(trie.NewIterator(nil, nil)); len(keys) != 0 {
+		t.Errorf("unexpected keys %v of the empty trie", keys)
+	}
+}
@@ -0,0 +1,121 @@
+package trie
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+// legacyRootKey is the key of the root node in the legacy JSON format.
+var legacyRootKey = []byte("root")
+
+// Migrate moves the trie with the given key length stored in the legacy
+// JSON format in one store to another store in the current format, as
+// its current root. It reports whether the legacy store held a trie.
+// The legacy format keys each node, including the leaves and the nodes
+// along the edges, by its path as a sequence of "0" and "1" characters,
+// and encodes it as JSON.
+//
+// The node never stored its own tries in the legacy format, so it runs
+// no migration on startup: Migrate is meant for the tries that other
+// programs stored with the trie package before the current format.
+//
+// The legacy hashes are kept, so the migration doesn't recompute any
+// hash. The legacy nodes are removed only after the trie is migrated,
+// and the root is removed last, so an interrupted migration resumes on
+// the next call.
+func Migrate(legacy, to store.Storer, keyLen int) bool {
+	l := legacyStore{legacy}
+	root, ok := l.get(nil)
+	if !ok {
+		return false
+	}
+
+	t := New(to, keyLen)
+	if _, ok := to.Get(rootKey); !ok {
+		t.setRoot(rootKey, t.convert(l, []byte{}, root))
+	}
+	l.remove([]byte{}, keyLen)
+	return true
+}
+
+// convert stores the subtree of the given legacy node with the given
+// path in the trie and returns its hash.
+func (t *Trie) convert(l legacyStore, path []byte, n Node) *big.Int {
+	height := len(path)
+	switch {
+	case height == t.keyLen:
+		// The leaves are not stored.
+		return n.Bottom
+	case n.Length > 0:
+		bottom := append([]byte{}, path...)
+		for i := int(n.Length) - 1; i >= 0; i-- {
+			bottom = append(bottom, 48 /* "0" */ +byte(n.Path.Bit(i)))
+		}
+		if len(bottom) < t.keyLen {
+			t.convert(l, bottom, l.mustGet(bottom))
+		}
+		t.storeNode(height, &EdgeNode{n.Encoding}, n.Hash)
+	default:
+		left := append(append([]byte{}, path...), 48 /* "0" */)
+		right := append(append([]byte{}, path...), 49 /* "1" */)
+		t.storeNode(height, &BinaryNode{
+			t.convert(l, left, l.mustGet(left)),
+			t.convert(l, right, l.mustGet(right)),
+		}, n.Hash)
+	}
+	return n.Hash
+}
+
+// legacyStore reads and removes the nodes of a trie stored in the
+// legacy JSON format.
+type legacyStore struct {
+	store store.Storer
+}
+
+// key returns the key of the node with the given path.
+func (l legacyStore) key(path []byte) []byte {
+	if len(path) == 0 {
+		return legacyRootKey
+	}
+	return path
+}
+
+// get returns the node with the given path and true if it was found.
+func (l legacyStore) get(path []byte) (Node, bool) {
+	b, ok := l.store.Get(l.key(path))
+	if !ok {
+		return Node{}, false
+	}
+	var n Node
+	if err := json.Unmarshal(b, &n); err != nil {
+		// notest
+		panic(any(fmt.Errorf("invalid legacy node %q: %w", path, err)))
+	}
+	return n, true
+}
+
+// mustGet is like get but panics if the node is missing.
+func (l legacyStore) mustGet(path []byte) Node {
+	n, ok := l.get(path)
+	if !ok {
+		// notest
+		panic(any(fmt.Errorf("missing legacy node %q", path)))
+	}
+	return n
+}
+
+// remove deletes the node with the given path from the legacy store,
+// after the nodes below it.
+func (l legacyStore) remove(path []byte, keyLen int) {
+	if _, ok := l.store.Get(l.key(path)); !ok {
+		return
+	}
+	if len(path) < keyLen {
+		l.remove(append(append([]byte{}, path...), 48 /* "0" */), keyLen)
+		l.remove(append(append([]byte{}, path...), 49 /* "1" */), keyLen)
+	}
+	l.store.Delete(l.key(path))
+}
@@ -0,0 +1,125 @@
+package trie
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// legacyNodes returns the nodes of the trie with the given key length
+// and key-value pairs by path, as the legacy formats stored them.
+func legacyNodes(keyLen int, pairs map[*big.Int]*big.Int) map[string]Node {
+	nodes := make(map[string]Node)
+	for key, val := range pairs {
+		if val.Sign() == 0 {
+			continue
+		}
+		path := Prefix(Reversed(key, keyLen), keyLen)
+		nodes[string(path)] = Node{Encoding{0, new(big.Int), val}, val}
+		for height := keyLen - 1; height >= 0; height-- {
+			parent := string(path[:height])
+			left, leftOk := nodes[parent+"0"]
+			right, rightOk := nodes[parent+"1"]
+			var n Node
+			switch {
+			case !rightOk:
+				n.Encoding = Encoding{left.Length + 1, left.Path, left.Bottom}
+			case !leftOk:
+				p := new(big.Int).Lsh(big.NewInt(1), uint(right.Length))
+				n.Encoding = Encoding{right.Length + 1, p.Add(p, right.Path), right.Bottom}
+			default:
+				n.Encoding = Encoding{0, new(big.Int), pedersen.Digest(left.Hash, right.Hash)}
+			}
+			n.hash()
+			nodes[parent] = n
+		}
+	}
+	return nodes
+}
+
+// toLegacy returns a store with the given nodes in the legacy JSON
+// format.
+func toLegacy(t *testing.T, nodes map[string]Node) mapStore {
+	legacy := mapStore{}
+	for path, n := range nodes {
+		b, err := json.Marshal(n)
+		if err != nil {
+			t.Fatalf("unexpected error encoding node %q: %s", path, err)
+		}
+		if path == "" {
+			path = "root"
+		}
+		legacy[path] = b
+	}
+	return legacy
+}
+
+func TestMigrate(t *testing.T) {
+	for _, keyLen := range []int{testKeyLen, 251} {
+		t.Run(fmt.Sprintf("key length %d", keyLen), func(t *testing.T) {
+			pairs := map[*big.Int]*big.Int{
+				new(big.Int).Lsh(big.NewInt(1), uint(keyLen-1)): big.NewInt(3),
+			}
+			want := New(mapStore{}, keyLen)
+			for _, test := range tests {
+				pairs[test.key] = test.val
+			}
+			for key, val := range pairs {
+				want.Put(key, val)
+			}
+			legacy := toLegacy(t, legacyNodes(keyLen, pairs))
+
+			got := New(mapStore{}, keyLen)
+			if !Migrate(legacy, got.store, keyLen) {
+				t.Fatalf("legacy trie not found")
+			}
+			if len(legacy) != 0 {
+				t.Errorf("%d nodes left in the legacy store", len(legacy))
+			}
+			if !equalStores(got.store.(mapStore), want.store.(mapStore)) {
+				t.Errorf("migrated nodes differ")
+			}
+			for key, val := range pairs {
+				if v, ok := got.Get(key); val.Sign() != 0 && (!ok || v.Cmp(val) != 0) {
+					t.Errorf("get(%#v) = %#v, want %#v", key, v, val)
+				}
+			}
+			if Migrate(legacy, got.store, keyLen) {
+				t.Errorf("trie migrated twice")
+			}
+		})
+	}
+}
+
+func TestMigrateResumes(t *testing.T) {
+	pairs := make(map[*big.Int]*big.Int)
+	want := New(mapStore{}, testKeyLen)
+	for _, test := range tests {
+		pairs[test.key] = test.val
+		want.Put(test.key, test.val)
+	}
+	legacy := toLegacy(t, legacyNodes(testKeyLen, pairs))
+
+	// An interrupted migration has stored the trie and removed the
+	// legacy subtree of the path "1" already.
+	got := New(mapStore{}, testKeyLen)
+	for key, val := range want.store.(mapStore) {
+		got.store.Put([]byte(key), val)
+	}
+	for _, path := range []string{"101", "10", "1"} {
+		delete(legacy, path)
+	}
+
+	if !Migrate(legacy, got.store, testKeyLen) {
+		t.Fatalf("legacy trie not found")
+	}
+	if len(legacy) != 0 {
+		t.Errorf("%d nodes left in the legacy store", len(legacy))
+	}
+	if !equalStores(got.store.(mapStore), want.store.(mapStore)) {
+		t.Errorf("migrated nodes differ")
+	}
+}
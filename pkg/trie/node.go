@@ -1,12 +1,20 @@
 package trie
 
 import (
-	"encoding/json"
+	"encoding/binary"
+	"errors"
 	"math/big"
 
 	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
 )
 
+// feltLen is the length in bytes of the field elements of the nodes
+// when encoded.
+const feltLen = 32
+
+// errInvalidNode is returned when decoding a malformed node.
+var errInvalidNode = errors.New("invalid node encoding")
+
 // Encoding represents the Encoding of a node in a binary tree
 // represented by the triplet (length, path, bottom).
 type Encoding struct {
@@ -21,12 +29,6 @@ type Node struct {
 	Hash *big.Int `json:"hash"`
 }
 
-// bytes returns a JSON byte representation of a node.
-func (n *Node) bytes() []byte {
-	b, _ := json.Marshal(n)
-	return b
-}
-
 // hash updates the node hash.
 func (n *Node) hash() {
 	if n.Length == 0 {
@@ -36,3 +38,39 @@ func (n *Node) hash() {
 	h := pedersen.Digest(n.Bottom, n.Path)
 	n.Hash = h.Add(h, new(big.Int).SetUint64(uint64(n.Length)))
 }
+
+// encodeNode returns the binary representation of a stored node, a
+// *BinaryNode or an *EdgeNode, with the given number of references to
+// it. Binary nodes are encoded as a zero byte followed by the hashes of
+// their children, and edge nodes as their length followed by their path
+// and bottom, each one as a 32-byte big-endian field element. The
+// number of references follows as a 4-byte big-endian integer.
+func encodeNode(n ProofNode, refs uint32) []byte {
+	b := make([]byte, 1+2*feltLen+4)
+	switch n := n.(type) {
+	case *BinaryNode:
+		n.LeftHash.FillBytes(b[1 : 1+feltLen])
+		n.RightHash.FillBytes(b[1+feltLen : 1+2*feltLen])
+	case *EdgeNode:
+		b[0] = n.Length
+		n.Path.FillBytes(b[1 : 1+feltLen])
+		n.Bottom.FillBytes(b[1+feltLen : 1+2*feltLen])
+	}
+	binary.BigEndian.PutUint32(b[1+2*feltLen:], refs)
+	return b
+}
+
+// decodeNode returns the node and the number of references to it with
+// the given binary representation, as returned by encodeNode.
+func decodeNode(b []byte) (ProofNode, uint32, error) {
+	if len(b) != 1+2*feltLen+4 {
+		return nil, 0, errInvalidNode
+	}
+	first := new(big.Int).SetBytes(b[1 : 1+feltLen])
+	second := new(big.Int).SetBytes(b[1+feltLen : 1+2*feltLen])
+	refs := binary.BigEndian.Uint32(b[1+2*feltLen:])
+	if b[0] == 0 {
+		return &BinaryNode{first, second}, refs, nil
+	}
+	return &EdgeNode{Encoding{b[0], first, second}}, refs, nil
+}
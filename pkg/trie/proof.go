@@ -0,0 +1,129 @@
+package trie
+
+import (
+	"math/big"
+
+	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
+)
+
+// ProofNode represents a node in a Merkle-Patricia proof, which is
+// either a *BinaryNode or an *EdgeNode.
+type ProofNode interface {
+	// Hash returns the hash of the node.
+	Hash() *big.Int
+}
+
+// BinaryNode represents a binary node in a proof by the hashes of its
+// children.
+type BinaryNode struct {
+	LeftHash  *big.Int `json:"left_hash"`
+	RightHash *big.Int `json:"right_hash"`
+}
+
+// Hash returns the hash of the binary node, H(left, right).
+func (n *BinaryNode) Hash() *big.Int {
+	return pedersen.Digest(n.LeftHash, n.RightHash)
+}
+
+// EdgeNode represents an edge node in a proof by its encoding, where
+// the bottom is the hash of the node at the end of the edge.
+type EdgeNode struct {
+	Encoding
+}
+
+// Hash returns the hash of the edge node, H(bottom, path) + length.
+func (n *EdgeNode) Hash() *big.Int {
+	h := pedersen.Digest(n.Bottom, n.Path)
+	return h.Add(h, new(big.Int).SetUint64(uint64(n.Length)))
+}
+
+// Prove returns the nodes on the way from the root of the trie to the
+// leaf with the given key, in that order. If the key is not in the
+// trie, the proof ends with the edge node that diverges from the key,
+// and serves as a proof of its absence. The proof of any key of an
+// empty trie is empty.
+func (t *Trie) Prove(key *big.Int) []ProofNode {
+	var proof []ProofNode
+	hash, ok := t.root(t.rootKey)
+	if !ok {
+		return proof
+	}
+	for height := 0; height < t.keyLen; {
+		n, _ := t.mustLoad(height, hash)
+		switch n := n.(type) {
+		case *BinaryNode:
+			proof = append(proof, &BinaryNode{
+				new(big.Int).Set(n.LeftHash), new(big.Int).Set(n.RightHash),
+			})
+			if key.Bit(t.keyLen-1-height) == 0 {
+				hash = n.LeftHash
+			} else {
+				hash = n.RightHash
+			}
+			height++
+		case *EdgeNode:
+			proof = append(proof, &EdgeNode{Encoding{
+				n.Length, new(big.Int).Set(n.Path), new(big.Int).Set(n.Bottom),
+			}})
+			if edgePath(key, t.keyLen, height, n.Length).Cmp(n.Path) != 0 {
+				return proof
+			}
+			hash = n.Bottom
+			height += int(n.Length)
+		}
+	}
+	return proof
+}
+
+// VerifyProof checks that the proof, as returned by Trie.Prove, proves
+// that the key has the given value in a trie with the given root and
+// key length. A zero value checks that the proof proves the absence of
+// the key.
+func VerifyProof(root, key, value *big.Int, proof []ProofNode, keyLen int) bool {
+	if len(proof) == 0 {
+		// The empty proof only proves the absence of the keys of an
+		// empty trie.
+		return root.Sign() == 0 && value.Sign() == 0
+	}
+
+	expected := root
+	height := 0
+	for i, node := range proof {
+		if height >= keyLen || node.Hash().Cmp(expected) != 0 {
+			return false
+		}
+
+		switch n := node.(type) {
+		case *BinaryNode:
+			if key.Bit(keyLen-1-height) == 0 {
+				expected = n.LeftHash
+			} else {
+				expected = n.RightHash
+			}
+			height++
+		case *EdgeNode:
+			if n.Length == 0 || height+int(n.Length) > keyLen {
+				return false
+			}
+			if edgePath(key, keyLen, height, n.Length).Cmp(n.Path) != 0 {
+				// The key leaves the trie at this edge, so it's absent,
+				// and the edge must be the last node of the proof.
+				return i == len(proof)-1 && value.Sign() == 0
+			}
+			expected = n.Bottom
+			height += int(n.Length)
+		default:
+			return false
+		}
+	}
+	return height == keyLen && value.Sign() != 0 && expected.Cmp(value) == 0
+}
+
+// edgePath returns the path that the key follows from the node at the
+// given height down the given number of levels, with the first step in
+// the most significant bit like in the path of an edge node.
+func edgePath(key *big.Int, keyLen, height int, length uint8) *big.Int {
+	path := new(big.Int).Rsh(key, uint(keyLen-height-int(length)))
+	mask := new(big.Int).Lsh(big.NewInt(1), uint(length))
+	return path.Mod(path, mask)
+}
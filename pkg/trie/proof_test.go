@@ -0,0 +1,110 @@
+package trie
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/NethermindEth/juno/pkg/store"
+)
+
+func TestProve(t *testing.T) {
+	trie := New(store.New(), testKeyLen)
+	for _, test := range tests {
+		trie.Put(test.key, test.val)
+	}
+	root := trie.Commitment()
+
+	// Every key of the trie, present or absent, has a proof of its value.
+	for i := int64(0); i < 1<<testKeyLen; i++ {
+		key := big.NewInt(i)
+		val, ok := trie.Get(key)
+		if !ok {
+			val = new(big.Int)
+		}
+		t.Run(fmt.Sprintf("prove(%#v)", key), func(t *testing.T) {
+			proof := trie.Prove(key)
+			if !VerifyProof(root, key, val, proof, testKeyLen) {
+				t.Fatalf("proof of value %#v does not verify", val)
+			}
+			if VerifyProof(root, key, big.NewInt(2), proof, testKeyLen) {
+				t.Errorf("proof verifies a wrong value")
+			}
+			if VerifyProof(new(big.Int).Add(root, big.NewInt(1)), key, val, proof, testKeyLen) {
+				t.Errorf("proof verifies against a wrong root")
+			}
+			if len(proof) > 1 && VerifyProof(root, key, val, proof[:len(proof)-1], testKeyLen) {
+				t.Errorf("truncated proof verifies")
+			}
+		})
+	}
+
+	t.Run("binary and edge nodes", func(t *testing.T) {
+		// The key 0b010 goes left from the root, then is the left child
+		// of a binary node at the end of an edge.
+		proof := trie.Prove(big.NewInt(2))
+		if len(proof) != 3 {
+			t.Fatalf("unexpected proof length %d, want 3", len(proof))
+		}
+		if _, ok := proof[0].(*BinaryNode); !ok {
+			t.Errorf("unexpected root node %T, want *BinaryNode", proof[0])
+		}
+		if edge, ok := proof[1].(*EdgeNode); !ok || edge.Length != 1 || edge.Path.Int64() != 1 {
+			t.Errorf("unexpected node %#v, want an edge with path 1 and length 1", proof[1])
+		}
+		if _, ok := proof[2].(*BinaryNode); !ok {
+			t.Errorf("unexpected node %T, want *BinaryNode", proof[2])
+		}
+	})
+
+	t.Run("tampered proof", func(t *testing.T) {
+		key := big.NewInt(5)
+		proof := trie.Prove(key)
+		edge := proof[len(proof)-1].(*EdgeNode)
+		edge.Bottom = big.NewInt(2)
+		if VerifyProof(root, key, big.NewInt(2), proof, testKeyLen) {
+			t.Errorf("tampered proof verifies")
+		}
+	})
+}
+
+func TestProveEmptyTrie(t *testing.T) {
+	trie := New(store.New(), testKeyLen)
+	key := big.NewInt(5)
+	proof := trie.Prove(key)
+	if len(proof) != 0 {
+		t.Fatalf("unexpected proof length %d, want 0", len(proof))
+	}
+	if !VerifyProof(trie.Commitment(), key, new(big.Int), proof, testKeyLen) {
+		t.Errorf("proof of absence does not verify")
+	}
+	if VerifyProof(trie.Commitment(), key, big.NewInt(1), proof, testKeyLen) {
+		t.Errorf("empty proof verifies a value")
+	}
+}
+
+func TestProveStorageTrie(t *testing.T) {
+	height := 251
+	trie := New(store.New(), height)
+	keys := []string{
+		"5",
+		"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620300",
+		"313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620301",
+	}
+	for i, k := range keys {
+		key, _ := new(big.Int).SetString(k, 16)
+		trie.Put(key, big.NewInt(int64(i+1)))
+	}
+	root := trie.Commitment()
+
+	for i, k := range keys {
+		key, _ := new(big.Int).SetString(k, 16)
+		if !VerifyProof(root, key, big.NewInt(int64(i+1)), trie.Prove(key), height) {
+			t.Errorf("proof of key %s does not verify", k)
+		}
+	}
+	absent, _ := new(big.Int).SetString("313ad57fdf765addc71329abf8d74ac2bce6d46da8c2b9b82255a5076620302", 16)
+	if !VerifyProof(root, absent, new(big.Int), trie.Prove(absent), height) {
+		t.Errorf("proof of absence does not verify")
+	}
+}
@@ -10,194 +10,436 @@
 //
 // # Worst-case time bounds for get and put operations
 //
-// The get operation is subject to the structure of the underlying data
-// store. For all intents and purposes this can be assumed to be
-// "constant" but in reality, if for example, a hashing based symbol
-// table is used as a backend to this structure, then the get operation
-// will take as much time as insertions take on that data structure.
+// Get operations read at most w nodes from the store, where w
+// represents the bit-length of the key, and in practice as many as
+// there are binary and edge nodes on the path of the key.
 //
-// Put and delete operations on the other hand take at most 1 + (w * 3)
-// database accesses where w represents the bit-length of the key.
+// Put and delete operations read the same nodes and write a new node
+// for each of them, together with the reference counts of their
+// children, so they take at most 1 + (w * 3) database accesses.
+//
+// An [Iterator] visits the keys of a range in order reading each node on
+// their paths once, so it takes at most n * w reads for n keys, but far
+// fewer as the paths of the keys share nodes.
+//
+// # Batches
+//
+// A [Batch] applies the updates of n keys at once, recomputing each
+// affected node once and keeping the writes in memory until the end of
+// the batch, so each node and reference count is written at most once.
+// That takes at most n * w node computations, but far fewer when the
+// paths of the keys share nodes, instead of n * w computations and
+// 1 + (w * 3) database accesses for each key.
+//
+// # Versions
+//
+// Nodes are immutable and keyed by their height and hash, so an update
+// writes new nodes rather than overwriting the old ones, and the tries
+// before and after the update share the nodes that did not change.
+// [Trie.Snapshot] records the current root under a version, usually a
+// block number, and [Trie.At] opens the trie as it was at that version.
+// Every node counts the references to it and is deleted with the nodes
+// below it once nothing refers to it, either the current root, a
+// version or another node, so the store holds the nodes of the current
+// trie and of the versions only. [NewWithPruning] keeps the given
+// number of latest versions and drops the older ones automatically.
+//
+// [Diff] compares two versions by walking both tries at once and
+// skipping the subtrees they share, so it reads only the nodes on the
+// paths of the changed keys.
 //
 // # Space
 //
-// The tree only stores non-empty nodes so the space complexity is n * w
-// where w is the key length.
+// The tree only stores binary and edge nodes, which are at most 2n for
+// n keys, and shares the unchanged nodes between versions. Each node is
+// encoded in binary with 32-byte field elements; use [Migrate] to
+// convert the tries stored by other programs in the legacy JSON format,
+// keyed by path.
 //
 // [Merkle-Patricia tree]: https://docs.starknet.io/docs/State/starknet-state#merkle-patricia-tree
 package trie
 
 import (
-	"encoding/json"
+	"encoding/binary"
+	"fmt"
 	"math/big"
 
-	"github.com/NethermindEth/juno/pkg/crypto/pedersen"
 	"github.com/NethermindEth/juno/pkg/store"
 )
 
-// A visualisation of the trie with 3-bit keys which results in a tree
-// of height 2 where empty nodes are not stored in the database.
-//
-//                           (0,0,r)
-//                           /     \
-//                        0 /       \ 1
-//                         /         \
-//                   (2,2,1)         (2,1,1)
-//                     / \             / \
-//                    /   \ 1       0 /   \
-//                         \         /
-//                       (1,0,1) (1,1,1)
-//                         / \     / \
-//                      0 /   \   /   \ 1
-//                       /             \
-//                   (0,0,1)        (0,0,1)
-//                     / \            / \
-//                    /   \          /   \
-//
-// Put and delete operations work by first committing (or removing) the
-// bottom node from the backend store and then traversing upwards to
-// compute the new node encodings and hashes which result in a new tree
-// commitment.
+// A visualisation of the trie with 3-bit keys 2, 3 and 5, each with the
+// value 1, where only the binary nodes (B) and edge nodes (E) are
+// stored, keyed by their height and hash. The leaves are the values
+// themselves.
+//
+//	                   B (height 0)
+//	                  / \
+//	               0 /   \ 1
+//	                /     \
+//	  (height 1) E(1,1)  E(2,1) (height 1)
+//	              |        |
+//	              | 1      | 01
+//	              |        |
+//	  (height 2)  B        1 (leaf, key 5)
+//	             / \
+//	          0 /   \ 1
+//	           /     \
+//	          1       1 (leaves, keys 2 and 3)
+//
+// Put and delete operations work by walking down the path of the key,
+// splitting the edges it crosses, and then walking back up to build the
+// new nodes, whose hashes result in a new tree commitment. The nodes
+// of the previous tree are released once the new root is in place.
+
+var (
+	// rootKey is the key of the current root in the store.
+	rootKey = []byte{'r'}
+	// versionsKey is the key of the list of versions in the store when
+	// the trie is pruned.
+	versionsKey = []byte{'l'}
+)
 
 // Trie represents a binary trie.
 type Trie struct {
 	keyLen int
 	store  store.Storer
+	// rootKey is the key of the root of the trie in the store, which is
+	// either the current root or the root of a version. It is nil for a
+	// trie returned by AtRoot, whose root is fixedRoot.
+	rootKey   []byte
+	fixedRoot *big.Int
+	// keep is the number of versions to keep, or zero or less to keep
+	// them all.
+	keep int
 }
 
-// New constructs a new binary trie.
+// New constructs a new binary trie that keeps all its versions.
 func New(store store.Storer, keyLen int) Trie {
-	return Trie{keyLen: keyLen, store: store}
+	return Trie{keyLen: keyLen, store: store, rootKey: rootKey}
+}
+
+// NewWithPruning constructs a new binary trie that keeps the given
+// number of latest versions. When a new version is recorded with
+// Snapshot, the oldest version is dropped if there are more than keep.
+// A keep of zero or less keeps every version, like New.
+func NewWithPruning(store store.Storer, keyLen, keep int) Trie {
+	return Trie{keyLen: keyLen, store: store, rootKey: rootKey, keep: keep}
+}
+
+// nodeKey returns the key of the node with the given height and hash in
+// the store.
+func nodeKey(height int, hash *big.Int) []byte {
+	key := make([]byte, 2+feltLen)
+	key[0] = 'n'
+	key[1] = byte(height)
+	hash.FillBytes(key[2:])
+	return key
+}
+
+// versionKey returns the key of the root of the given version in the
+// store.
+func versionKey(version uint64) []byte {
+	key := make([]byte, 9)
+	key[0] = 'v'
+	binary.BigEndian.PutUint64(key[1:], version)
+	return key
+}
+
+// load returns the node with the given height and hash and the number
+// of references to it.
+func (t *Trie) load(height int, hash *big.Int) (ProofNode, uint32, bool) {
+	b, ok := t.store.Get(nodeKey(height, hash))
+	if !ok {
+		return nil, 0, false
+	}
+	n, refs, err := decodeNode(b)
+	if err != nil {
+		// notest
+		panic(any(fmt.Errorf("node %x at height %d: %w", hash, height, err)))
+	}
+	return n, refs, true
 }
 
-// commit persists the given key-value pair in storage.
-func (t *Trie) commit(key, val []byte) {
-	if len(key) == 0 {
-		key = []byte("root")
+// mustLoad is like load but panics if the node is missing, which means
+// the store is corrupt.
+func (t *Trie) mustLoad(height int, hash *big.Int) (ProofNode, uint32) {
+	n, refs, ok := t.load(height, hash)
+	if !ok {
+		// notest
+		panic(any(fmt.Errorf("missing node %x at height %d", hash, height)))
 	}
-	t.store.Put(key, val)
+	return n, refs
 }
 
-// remove deletes a key-value pair from storage.
-func (t *Trie) remove(key []byte) {
-	if len(key) == 0 {
-		key = []byte("root")
+// storeNode persists the given node with the given height and hash if
+// it isn't stored yet, adding a reference to each of its children. The
+// node itself starts with no references.
+func (t *Trie) storeNode(height int, n ProofNode, hash *big.Int) {
+	key := nodeKey(height, hash)
+	if _, ok := t.store.Get(key); ok {
+		return
 	}
-	t.store.Delete(key)
+	// The children are referenced before the node is written, so an
+	// interrupted write may leak them but never frees them early.
+	t.forChildren(height, n, t.incref)
+	t.store.Put(key, encodeNode(n, 0))
 }
 
-// retrieve gets a node from storage and returns true if the node was
-// found.
-func (t *Trie) retrieve(key []byte) (Node, bool) {
-	if len(key) == 0 {
-		key = []byte("root")
+// forChildren calls f with the height and hash of each of the children
+// of the given node that are stored, that is, that are not leaves.
+func (t *Trie) forChildren(height int, n ProofNode, f func(int, *big.Int)) {
+	switch n := n.(type) {
+	case *BinaryNode:
+		if height+1 < t.keyLen {
+			f(height+1, n.LeftHash)
+			f(height+1, n.RightHash)
+		}
+	case *EdgeNode:
+		if height+int(n.Length) < t.keyLen {
+			f(height+int(n.Length), n.Bottom)
+		}
+	}
+}
+
+// incref adds a reference to the node with the given height and hash.
+func (t *Trie) incref(height int, hash *big.Int) {
+	n, refs := t.mustLoad(height, hash)
+	t.store.Put(nodeKey(height, hash), encodeNode(n, refs+1))
+}
+
+// decref removes a reference to the node with the given height and
+// hash, and deletes it together with the nodes below it that are no
+// longer referenced if it was the last one.
+func (t *Trie) decref(height int, hash *big.Int) {
+	n, refs := t.mustLoad(height, hash)
+	if refs > 1 {
+		t.store.Put(nodeKey(height, hash), encodeNode(n, refs-1))
+		return
+	}
+	t.store.Delete(nodeKey(height, hash))
+	t.forChildren(height, n, t.decref)
+}
+
+// root returns the root hash stored under the given key, and false if
+// there is none or the trie is empty. A nil key stands for the fixed
+// root of the trie.
+func (t *Trie) root(key []byte) (*big.Int, bool) {
+	if key == nil {
+		return t.fixedRoot, t.fixedRoot.Sign() != 0
 	}
 	b, ok := t.store.Get(key)
 	if !ok {
-		return Node{}, false
+		return nil, false
 	}
-	var n Node
-	if err := json.Unmarshal(b, &n); err != nil {
-		// notest
-		return Node{}, false
+	root := new(big.Int).SetBytes(b)
+	return root, root.Sign() != 0
+}
+
+// setRoot points the given key to the given root, adding a reference to
+// the new root and removing one from the previous root. A zero root
+// stands for an empty trie: the current root is then deleted, while
+// versions keep the zero root so that they still exist.
+func (t *Trie) setRoot(key []byte, root *big.Int) {
+	if root.Sign() != 0 {
+		t.incref(0, root)
+	}
+	old, ok := t.root(key)
+	if root.Sign() == 0 && key[0] != 'v' {
+		t.store.Delete(key)
+	} else {
+		t.store.Put(key, root.FillBytes(make([]byte, feltLen)))
+	}
+	if ok {
+		t.decref(0, old)
 	}
-	return n, true
 }
 
-// diff traverses the tree upwards from the given path (key) starting
-// from the node that immediately precedes the bottom node and either
-// deletes the node if it its child nodes are empty or recomputes the
-// encoding and hashes otherwise.
-func (t *Trie) diff(key *big.Int) {
-	for height := t.keyLen - 1; height >= 0; height-- {
-		parent := Prefix(key, height)
+// update is a pending change of the value of a key.
+type update struct {
+	key, val *big.Int
+}
 
-		leftChild, leftChildIsNotEmpty := t.retrieve(append(parent, 48 /* "0" */))
-		rightChild, rightChildIsNotEmpty := t.retrieve(append(parent, 49 /* "1" */))
+// apply applies the given updates, with distinct keys, to the trie.
+func (t *Trie) apply(updates []update) {
+	if t.rootKey == nil {
+		panic(any("trie: update of a trie with a fixed root"))
+	}
+	var root *Node
+	if hash, ok := t.root(t.rootKey); ok {
+		root = t.subtree(0, hash)
+	}
+	root = t.update(0, root, updates)
+	if root == nil {
+		t.setRoot(t.rootKey, new(big.Int))
+		return
+	}
+	t.setRoot(t.rootKey, t.put(0, root))
+}
 
-		if !leftChildIsNotEmpty && !rightChildIsNotEmpty {
-			t.remove(parent)
+// update returns the subtree at the given height, starting at the given
+// node, with the given updates applied to it. A nil node is an empty
+// subtree.
+//
+// The subtree is represented by a node whose encoding is either an
+// edge, with a nonzero length, to the binary node or leaf whose hash is
+// the bottom, or, with a zero length, the binary node or leaf itself.
+// New edges are only stored once the parent of the subtree is.
+func (t *Trie) update(height int, n *Node, updates []update) *Node {
+	if height == t.keyLen {
+		// The updates of a leaf have the same key.
+		val := updates[len(updates)-1].val
+		if val.Sign() == 0 {
+			return nil
+		}
+		return &Node{Encoding{0, new(big.Int), val}, val}
+	}
+
+	left, right := t.children(height, n)
+	var leftUpdates, rightUpdates []update
+	for _, u := range updates {
+		if u.key.Bit(t.keyLen-1-height) == 0 {
+			leftUpdates = append(leftUpdates, u)
 		} else {
-			// Overwrite the parent node.
-			n := new(Node)
-
-			// Compute its encoding.
-			switch {
-			case !rightChildIsNotEmpty:
-				n.Encoding = Encoding{
-					leftChild.Length + 1, leftChild.Path, new(big.Int).Set(leftChild.Bottom),
-				}
-			case !leftChildIsNotEmpty:
-				n.Encoding = Encoding{
-					rightChild.Length + 1,
-					rightChild.Path.Add(
-						rightChild.Path,
-						new(big.Int).Exp(
-							new(big.Int).SetUint64(2),
-							new(big.Int).SetUint64(uint64(rightChild.Length)), nil)),
-					new(big.Int).Set(rightChild.Bottom),
-				}
-			default:
-				n.Encoding = Encoding{
-					0, new(big.Int), pedersen.Digest(leftChild.Hash, rightChild.Hash),
-				}
-			}
+			rightUpdates = append(rightUpdates, u)
+		}
+	}
+	if len(leftUpdates) > 0 {
+		left = t.update(height+1, left, leftUpdates)
+	}
+	if len(rightUpdates) > 0 {
+		right = t.update(height+1, right, rightUpdates)
+	}
+	return t.join(height, left, right)
+}
 
-			// Compute its hash.
-			n.hash()
+// children returns the subtrees below the given subtree at the given
+// height, where a nil node is an empty subtree.
+func (t *Trie) children(height int, n *Node) (left, right *Node) {
+	if n == nil {
+		return nil, nil
+	}
+	if n.Length > 0 {
+		// The edge loses its first step, which is its most significant
+		// bit.
+		length := uint(n.Length - 1)
+		child := &Node{Encoding{0, new(big.Int), n.Bottom}, n.Bottom}
+		if length > 0 {
+			path := new(big.Int).Mod(n.Path, new(big.Int).Lsh(big.NewInt(1), length))
+			child = &Node{Encoding{uint8(length), path, n.Bottom}, nil}
+		}
+		if n.Path.Bit(int(length)) == 0 {
+			return child, nil
+		}
+		return nil, child
+	}
+	node, _ := t.mustLoad(height, n.Bottom)
+	binary := node.(*BinaryNode)
+	return t.subtree(height+1, binary.LeftHash), t.subtree(height+1, binary.RightHash)
+}
 
-			// Commit to the database.
-			t.commit(parent, n.bytes())
+// subtree returns the subtree whose root is the node or leaf with the
+// given height and hash.
+func (t *Trie) subtree(height int, hash *big.Int) *Node {
+	if height < t.keyLen {
+		n, _ := t.mustLoad(height, hash)
+		if edge, ok := n.(*EdgeNode); ok {
+			return &Node{edge.Encoding, hash}
 		}
 	}
+	return &Node{Encoding{0, new(big.Int), hash}, hash}
+}
+
+// join returns the subtree at the given height whose children are the
+// given subtrees, where a nil node is an empty subtree, storing a new
+// binary node if both are not empty.
+func (t *Trie) join(height int, left, right *Node) *Node {
+	switch {
+	case left == nil && right == nil:
+		return nil
+	case right == nil:
+		return &Node{Encoding{left.Length + 1, left.Path, left.Bottom}, nil}
+	case left == nil:
+		path := new(big.Int).Lsh(big.NewInt(1), uint(right.Length))
+		return &Node{Encoding{right.Length + 1, path.Add(path, right.Path), right.Bottom}, nil}
+	default:
+		n := &BinaryNode{t.put(height+1, left), t.put(height+1, right)}
+		hash := n.Hash()
+		t.storeNode(height, n, hash)
+		return &Node{Encoding{0, new(big.Int), hash}, hash}
+	}
+}
+
+// put stores the given subtree at the given height, if it is an edge,
+// and returns its hash.
+func (t *Trie) put(height int, n *Node) *big.Int {
+	if n.Length == 0 {
+		return n.Bottom
+	}
+	if n.Hash == nil {
+		n.hash()
+	}
+	t.storeNode(height, &EdgeNode{n.Encoding}, n.Hash)
+	return n.Hash
 }
 
 // Delete removes a key-value pair from the trie.
 func (t *Trie) Delete(key *big.Int) {
-	// The internal representation of big.Int has the least significant
-	// bit in the 0th position but this algorithm assumes the oppose so
-	// a copy with the bits reversed is used instead.
-	rev := Reversed(key, t.keyLen)
-
-	t.remove(Prefix(rev, t.keyLen))
-	t.diff(rev)
+	t.Put(key, new(big.Int))
 }
 
 // Get retrieves a value from the trie with the corresponding key.
 func (t *Trie) Get(key *big.Int) (*big.Int, bool) {
-	// The internal representation of big.Int has the least significant
-	// bit in the 0th position but this algorithm assumes the opposite so
-	// a copy with the bits reversed is passed into the function.
-	node, ok := t.retrieve(Prefix(Reversed(key, t.keyLen), t.keyLen))
+	hash, ok := t.root(t.rootKey)
 	if !ok {
 		return nil, false
 	}
-	return node.Bottom, true
+	for height := 0; height < t.keyLen; {
+		n, _ := t.mustLoad(height, hash)
+		switch n := n.(type) {
+		case *BinaryNode:
+			if key.Bit(t.keyLen-1-height) == 0 {
+				hash = n.LeftHash
+			} else {
+				hash = n.RightHash
+			}
+			height++
+		case *EdgeNode:
+			if edgePath(key, t.keyLen, height, n.Length).Cmp(n.Path) != 0 {
+				return nil, false
+			}
+			hash = n.Bottom
+			height += int(n.Length)
+		}
+	}
+	return hash, true
 }
 
-// Put inserts a [big.Int] key-value pair in the trie.
+// Put inserts a [big.Int] key-value pair in the trie. A zero value
+// removes the key.
 func (t *Trie) Put(key, val *big.Int) {
-	if val.Cmp(new(big.Int)) == 0 {
-		t.Delete(key)
-		return
-	}
-
-	// The internal representation of big.Int has the least significant
-	// bit in the 0th position but this algorithm assumes the oppose so a
-	// copy with the bits reversed is used instead.
-	rev := Reversed(key, t.keyLen)
-
-	leaf := Node{Encoding{0, new(big.Int), val}, val}
-	t.commit(Prefix(rev, t.keyLen), leaf.bytes())
-	t.diff(rev)
+	t.apply([]update{{new(big.Int).Set(key), new(big.Int).Set(val)}})
 }
 
 // Commitment returns the root hash of the trie. If the tree is empty,
-// this value is nil.
+// this value is zero.
 func (t *Trie) Commitment() *big.Int {
-	root, ok := t.retrieve([]byte{})
+	root, ok := t.root(t.rootKey)
 	if !ok {
 		return new(big.Int)
 	}
-	return root.Hash
+	return root
+}
+
+// AtRoot returns the trie with the given root, which must be the root of
+// the current trie or of one of its versions. A zero root is an empty
+// trie. The returned trie is read only.
+func (t *Trie) AtRoot(root *big.Int) Trie {
+	return Trie{keyLen: t.keyLen, store: t.store, fixedRoot: new(big.Int).Set(root)}
+}
+
+// Reset points the current trie to the given root, which must be the
+// root of one of its versions, undoing the updates made since. A zero
+// root empties the trie.
+func (t *Trie) Reset(root *big.Int) {
+	t.setRoot(t.rootKey, root)
 }
@@ -1,7 +1,6 @@
 package trie
 
 import (
-	"encoding/json"
 	"fmt"
 	"math/big"
 	"math/rand"
@@ -185,20 +184,16 @@ func TestPut(t *testing.T) {
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("put(%#v, %#v)", test.key, test.val), func(t *testing.T) {
 			trie.Put(test.key, test.val)
-			pre := Prefix(Reversed(test.key, testKeyLen), testKeyLen)
-			got, ok := db.Get(pre)
+			rebuilt := New(db, testKeyLen)
+			got, ok := rebuilt.Get(test.key)
 			if !ok {
 				// A key with a value 0 is deleted.
 				if test.val.Cmp(new(big.Int)) == 0 {
 					t.Skip()
 				}
-				t.Fatalf("failed to retrieve value with key %s from database", pre)
+				t.Fatalf("failed to retrieve key %#v from database", test.key)
 			}
-			var n Node
-			if err := json.Unmarshal(got, &n); err != nil {
-				t.Fatal("failed to unmarshal value from database")
-			}
-			if test.val.Cmp(n.Bottom) != 0 {
+			if test.val.Cmp(got) != 0 {
 				t.Errorf("failed to put value %#v at key %#v", test.key, test.val)
 			}
 		})
@@ -0,0 +1,91 @@
+package trie
+
+import "encoding/binary"
+
+// Snapshot records the current root of the trie as the given version,
+// replacing the root previously recorded under it, if any. The nodes of
+// the version are kept until it is dropped. If the trie is pruned and
+// keeps fewer versions than it now has, the oldest ones are dropped.
+func (t *Trie) Snapshot(version uint64) {
+	t.setRoot(versionKey(version), t.Commitment())
+	if t.keep <= 0 {
+		return
+	}
+
+	versions := t.versions()
+	for _, v := range versions {
+		if v == version {
+			return
+		}
+	}
+	versions = append(versions, version)
+	for len(versions) > t.keep {
+		t.dropRoot(versions[0])
+		versions = versions[1:]
+	}
+	t.setVersions(versions)
+}
+
+// At returns the trie as it was recorded at the given version, and
+// false if there is no such version. The returned trie is meant to be
+// read only: updating it changes the version in place.
+func (t *Trie) At(version uint64) (Trie, bool) {
+	key := versionKey(version)
+	if _, ok := t.store.Get(key); !ok {
+		return Trie{}, false
+	}
+	return Trie{keyLen: t.keyLen, store: t.store, rootKey: key}, true
+}
+
+// DropVersion removes the given version, releasing the nodes that only
+// that version used. It does nothing if there is no such version.
+func (t *Trie) DropVersion(version uint64) {
+	t.dropRoot(version)
+	if t.keep <= 0 {
+		return
+	}
+	versions := t.versions()
+	for i, v := range versions {
+		if v == version {
+			t.setVersions(append(versions[:i], versions[i+1:]...))
+			return
+		}
+	}
+}
+
+// dropRoot deletes the root of the given version and releases it.
+func (t *Trie) dropRoot(version uint64) {
+	key := versionKey(version)
+	if _, ok := t.store.Get(key); !ok {
+		return
+	}
+	root, ok := t.root(key)
+	t.store.Delete(key)
+	if ok {
+		t.decref(0, root)
+	}
+}
+
+// versions returns the versions kept by a pruned trie from the oldest
+// to the newest.
+func (t *Trie) versions() []uint64 {
+	b, _ := t.store.Get(versionsKey)
+	versions := make([]uint64, len(b)/8)
+	for i := range versions {
+		versions[i] = binary.BigEndian.Uint64(b[8*i:])
+	}
+	return versions
+}
+
+// setVersions stores the versions kept by a pruned trie.
+func (t *Trie) setVersions(versions []uint64) {
+	if len(versions) == 0 {
+		t.store.Delete(versionsKey)
+		return
+	}
+	b := make([]byte, 8*len(versions))
+	for i, v := range versions {
+		binary.BigEndian.PutUint64(b[8*i:], v)
+	}
+	t.store.Put(versionsKey, b)
+}
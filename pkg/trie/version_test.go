@@ -0,0 +1,153 @@
+package trie
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// putVersion puts the keys of the tests with the values multiplied by
+// the given factor and records the result as the given version. It
+// returns the commitment of the version.
+func putVersion(trie *Trie, version uint64, factor int64) *big.Int {
+	for _, test := range tests {
+		trie.Put(test.key, new(big.Int).Mul(test.val, big.NewInt(factor)))
+	}
+	trie.Snapshot(version)
+	return trie.Commitment()
+}
+
+// checkVersion checks that the given version of the trie has the
+// values of the tests multiplied by the given factor.
+func checkVersion(t *testing.T, trie *Trie, version uint64, factor int64, root *big.Int) {
+	t.Helper()
+	old, ok := trie.At(version)
+	if !ok {
+		t.Fatalf("version %d not found", version)
+	}
+	if got := old.Commitment(); got.Cmp(root) != 0 {
+		t.Errorf("version %d: commitment %x, want %x", version, got, root)
+	}
+	for _, test := range tests {
+		want := new(big.Int).Mul(test.val, big.NewInt(factor))
+		got, ok := old.Get(test.key)
+		if want.Sign() == 0 {
+			if ok {
+				t.Errorf("version %d: get(%#v) = %#v, want no value", version, test.key, got)
+			}
+			continue
+		}
+		if !ok || got.Cmp(want) != 0 {
+			t.Errorf("version %d: get(%#v) = %#v, want %#v", version, test.key, got, want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	roots := make(map[uint64]*big.Int)
+	for version := uint64(1); version <= 3; version++ {
+		roots[version] = putVersion(&trie, version, int64(version))
+	}
+	// The current trie moves on without changing the versions.
+	trie.Delete(tests[0].key)
+
+	for version := uint64(1); version <= 3; version++ {
+		t.Run(fmt.Sprintf("at(%d)", version), func(t *testing.T) {
+			checkVersion(t, &trie, version, int64(version), roots[version])
+		})
+	}
+	if _, ok := trie.At(4); ok {
+		t.Errorf("unknown version found")
+	}
+
+	// An empty trie is a version too.
+	for _, test := range tests {
+		trie.Delete(test.key)
+	}
+	trie.Snapshot(4)
+	if old, ok := trie.At(4); !ok || old.Commitment().Sign() != 0 {
+		t.Errorf("empty version not found")
+	}
+
+	// Dropping every version releases every node.
+	trie.DropVersion(2)
+	if _, ok := trie.At(2); ok {
+		t.Errorf("dropped version found")
+	}
+	checkVersion(t, &trie, 3, 3, roots[3])
+	for _, version := range []uint64{1, 3, 4} {
+		trie.DropVersion(version)
+	}
+	if len(trie.store.(mapStore)) != 0 {
+		t.Errorf("%d entries left after dropping every version", len(trie.store.(mapStore)))
+	}
+}
+
+func TestSnapshotReplacesVersion(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	putVersion(&trie, 1, 1)
+	root := putVersion(&trie, 1, 2)
+	checkVersion(t, &trie, 1, 2, root)
+
+	want := New(mapStore{}, testKeyLen)
+	putVersion(&want, 1, 2)
+	if !equalStores(trie.store.(mapStore), want.store.(mapStore)) {
+		t.Errorf("replaced version left nodes behind")
+	}
+}
+
+func TestPruning(t *testing.T) {
+	const keep = 2
+	trie := NewWithPruning(mapStore{}, testKeyLen, keep)
+	roots := make(map[uint64]*big.Int)
+	for version := uint64(1); version <= 5; version++ {
+		roots[version] = putVersion(&trie, version, int64(version))
+	}
+
+	for version := uint64(1); version <= 5; version++ {
+		if version <= 5-keep {
+			if _, ok := trie.At(version); ok {
+				t.Errorf("pruned version %d found", version)
+			}
+			continue
+		}
+		checkVersion(t, &trie, version, int64(version), roots[version])
+	}
+
+	// The pruned versions released their nodes.
+	want := NewWithPruning(mapStore{}, testKeyLen, keep)
+	for version := uint64(4); version <= 5; version++ {
+		putVersion(&want, version, int64(version))
+	}
+	if !equalStores(trie.store.(mapStore), want.store.(mapStore)) {
+		t.Errorf("pruned versions left nodes behind")
+	}
+}
+
+func TestAtRootAndReset(t *testing.T) {
+	trie := New(mapStore{}, testKeyLen)
+	root := putVersion(&trie, 1, 1)
+	putVersion(&trie, 2, 2)
+
+	old := trie.AtRoot(root)
+	if old.Commitment().Cmp(root) != 0 {
+		t.Errorf("commitment %x at root %x", old.Commitment(), root)
+	}
+	if got, ok := old.Get(tests[0].key); !ok || got.Cmp(tests[0].val) != 0 {
+		t.Errorf("get(%#v) = %#v at root %x, want %#v", tests[0].key, got, root, tests[0].val)
+	}
+	if empty := trie.AtRoot(new(big.Int)); empty.Commitment().Sign() != 0 {
+		t.Errorf("zero root is not empty")
+	}
+
+	// Resetting to the root of the first version and dropping the second
+	// leaves the same nodes as never making the second version.
+	trie.Reset(root)
+	trie.DropVersion(2)
+	want := New(mapStore{}, testKeyLen)
+	putVersion(&want, 1, 1)
+	if !equalStores(trie.store.(mapStore), want.store.(mapStore)) {
+		t.Errorf("reset left nodes behind")
+	}
+}